@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/crosswalk"
+	"github.com/solisamicus/occstructor/internal/service"
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+func main() {
+	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+	var edition = flag.String("edition", "", "Edition for -lookup and default source edition for -import (overrides config)")
+	var importPath = flag.String("import", "", "Import a crosswalk CSV (source_edition,source_seq,target_system,target_code,relation,note)")
+	var targetSystem = flag.String("target", "", "Default target system for CSVs without a target_system column: isco08 or an edition")
+	var relation = flag.String("relation", crosswalk.RelationExact, "Default relation for CSVs without a relation column")
+	var dryRun = flag.Bool("dry-run", false, "Validate the CSV against the database without importing")
+	var validate = flag.Bool("validate", false, "Re-validate all stored mappings")
+	var lookup = flag.String("lookup", "", "Print the mappings of this seq in -edition")
+	flag.Parse()
+
+	if *importPath == "" && !*validate && *lookup == "" {
+		log.Fatal("one of -import, -validate or -lookup is required")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *edition != "" {
+		cfg.Edition = *edition
+	}
+
+	db, err := database.NewConnection(cfg.GetDSN())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	crosswalkService := service.NewCrosswalkService(db)
+
+	if *importPath != "" {
+		entries, err := crosswalk.ReadCSVFile(*importPath, crosswalk.Entry{
+			SourceEdition: cfg.Edition,
+			TargetSystem:  *targetSystem,
+			Relation:      *relation,
+		})
+		if err != nil {
+			log.Fatalf("Failed to read crosswalk: %v", err)
+		}
+		fmt.Printf("Read %d mappings from %s\n", len(entries), *importPath)
+
+		var problems []crosswalk.Problem
+		if *dryRun {
+			problems, err = crosswalkService.Validate(entries)
+		} else {
+			problems, err = crosswalkService.Import(entries)
+		}
+		printProblems(problems)
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		if *dryRun {
+			fmt.Println("Dry run: all mappings reference existing seqs")
+		}
+	}
+
+	if *validate {
+		problems, err := crosswalkService.ValidateStored()
+		if err != nil {
+			log.Fatalf("Validation failed: %v", err)
+		}
+		printProblems(problems)
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("All stored mappings are valid")
+	}
+
+	if *lookup != "" {
+		mappings, err := crosswalkService.Lookup(cfg.Edition, *lookup)
+		if err != nil {
+			log.Fatalf("Lookup failed: %v", err)
+		}
+		if len(mappings) == 0 {
+			fmt.Printf("No mappings for %s in edition %s\n", *lookup, cfg.Edition)
+			return
+		}
+		for _, m := range mappings {
+			fmt.Printf("%s %-12s → %-7s %-12s %s\n", cfg.Edition, *lookup, m.System, m.Code, m.Relation)
+		}
+	}
+}
+
+func printProblems(problems []crosswalk.Problem) {
+	for _, p := range problems {
+		fmt.Printf("  Invalid: %s %-12s → %-7s %-12s %s\n", p.SourceEdition, p.SourceSeq, p.TargetSystem, p.TargetCode, p.Reason)
+	}
+}
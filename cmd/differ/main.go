@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/diff"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/repository"
+	"github.com/solisamicus/occstructor/internal/service"
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+func main() {
+	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+	var oldSide = flag.String("old", "", "Old side: an edition in the database (e.g. 2015) or an export JSON file")
+	var newSide = flag.String("new", "", "New side: an edition in the database (e.g. 2022) or an export JSON file")
+	var threshold = flag.Float64("threshold", diff.DefaultThreshold, "Name similarity threshold for rename detection (0-1)")
+	var output = flag.String("output", "", "Output path without extension, writes .md and .json (default: exports/diff_OLD_NEW_TIMESTAMP)")
+	flag.Parse()
+
+	if *oldSide == "" || *newSide == "" {
+		log.Fatal("-old and -new are required")
+	}
+
+	loader := &loader{configPath: *configPath}
+	defer loader.close()
+
+	oldLabel, oldNodes := loader.load(*oldSide)
+	newLabel, newNodes := loader.load(*newSide)
+	fmt.Printf("Comparing %s (%d records) with %s (%d records)\n", oldLabel, len(oldNodes), newLabel, len(newNodes))
+
+	report := diff.NewReport(oldLabel, oldNodes, newLabel, newNodes, diff.Options{Threshold: *threshold})
+
+	base := *output
+	if base == "" {
+		timestamp := time.Now().Format("20060102_150405")
+		base = filepath.Join("exports", fmt.Sprintf("diff_%s_%s_%s", safeLabel(oldLabel), safeLabel(newLabel), timestamp))
+	}
+	if err := report.WriteMarkdownFile(base + ".md"); err != nil {
+		log.Fatalf("Failed to write markdown report: %v", err)
+	}
+	if err := report.WriteJSON(base + ".json"); err != nil {
+		log.Fatalf("Failed to write JSON report: %v", err)
+	}
+
+	for _, kind := range diff.Kinds {
+		fmt.Printf("  %-8s %d\n", kind, report.Summary[kind])
+	}
+	fmt.Printf("Diff written to: %s.md, %s.json\n", base, base)
+}
+
+// loader 按需连接数据库，两侧均为导出文件时无需数据库
+type loader struct {
+	configPath string
+	db         *database.DB
+}
+
+func (l *loader) load(side string) (string, []*model.OccupationNode) {
+	if strings.HasSuffix(strings.ToLower(side), ".json") {
+		edition, nodes, err := service.LoadExportFile(side)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", side, err)
+		}
+		label := filepath.Base(side)
+		if edition != "" {
+			label = fmt.Sprintf("%s (%s)", edition, label)
+		}
+		return label, nodes
+	}
+
+	if l.db == nil {
+		cfg, err := config.LoadConfig(l.configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		l.db, err = database.NewConnection(cfg.GetDSN())
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+	}
+
+	nodes, err := repository.NewOccupationRepository(l.db, side).GetAll()
+	if err != nil {
+		log.Fatalf("Failed to load edition %s: %v", side, err)
+	}
+	if len(nodes) == 0 {
+		log.Fatalf("Edition %s has no records in the database", side)
+	}
+	return side, nodes
+}
+
+func (l *loader) close() {
+	if l.db != nil {
+		l.db.Close()
+	}
+}
+
+// safeLabel 将标签转换为可用于文件名的形式
+func safeLabel(label string) string {
+	label, _, _ = strings.Cut(label, " ")
+	return strings.TrimSuffix(label, filepath.Ext(label))
+}
@@ -15,15 +15,21 @@ import (
 
 func main() {
 	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+	var edition = flag.String("edition", "", "Edition of the classification, e.g. 1999, 2015 or 2022 (overrides config)")
 	var output = flag.String("output", "", "Output file path (default: exports/occupations_FORMAT_TIMESTAMP.json)")
 	var format = flag.String("format", "tree", "Export format: tree or flat")
 	var includeStats = flag.Bool("stats", true, "Include statistics in export")
+	var withProvenance = flag.Bool("with-provenance", false, "Include source sheet/row/column/raw text of each record")
+	var withMappings = flag.Bool("with-mappings", false, "Include ISCO-08 and cross-edition codes from the crosswalk table")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *edition != "" {
+		cfg.Edition = *edition
+	}
 
 	db, err := database.NewConnection(cfg.GetDSN())
 	if err != nil {
@@ -34,16 +40,18 @@ func main() {
 	outputPath := *output
 	if outputPath == "" {
 		timestamp := time.Now().Format("20060102_150405")
-		outputPath = filepath.Join("exports", fmt.Sprintf("occupations_%s_%s.json", *format, timestamp))
+		outputPath = filepath.Join("exports", fmt.Sprintf("occupations_%s_%s_%s.json", cfg.Edition, *format, timestamp))
 	}
 
-	repo := repository.NewOccupationRepository(db)
-	exportService := service.NewExportService(repo)
+	repo := repository.NewOccupationRepository(db, cfg.Edition)
+	exportService := service.NewExportService(repo, repository.NewCrosswalkRepository(db))
 
 	options := &service.ExportOptions{
-		OutputPath:   outputPath,
-		Format:       *format,
-		IncludeStats: *includeStats,
+		OutputPath:     outputPath,
+		Format:         *format,
+		IncludeStats:   *includeStats,
+		WithProvenance: *withProvenance,
+		WithMappings:   *withMappings,
 	}
 
 	fmt.Printf("Starting export (format: %s)...\n", *format)
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/parser"
+	"github.com/solisamicus/occstructor/internal/repository"
+	"github.com/solisamicus/occstructor/internal/service"
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+func main() {
+	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+	var edition = flag.String("edition", "", "Edition of the classification, e.g. 1999, 2015 or 2022 (overrides config)")
+	var logPath = flag.String("log", "", "Path to mismatch log (logs/mismatch_TIMESTAMP.jsonl)")
+	var file = flag.String("file", "", "Only process records of this workbook (path or file name)")
+	var sheet = flag.String("sheet", "", "Only process records of this sheet")
+	var line = flag.Int("line", 0, "Only process the record of this Excel row (0 = all)")
+	var pairs = flag.String("pairs", "", "Corrected pairing for -line, e.g. \"2-03-06-01=兽医,2-03-06-02=兽药技术人员\"")
+	var acceptSuggested = flag.Bool("accept-suggested", false, "Apply the suggested pairing of records without a fixed pairing")
+	var dryRun = flag.Bool("dry-run", false, "Print the resulting nodes without writing to the database")
+	flag.Parse()
+
+	if *logPath == "" {
+		log.Fatal("-log is required")
+	}
+	if *pairs != "" && *line == 0 {
+		log.Fatal("-pairs requires -line")
+	}
+
+	mismatches, err := parser.ReadMismatchLog(*logPath)
+	if err != nil {
+		log.Fatalf("Failed to read mismatch log: %v", err)
+	}
+
+	if *file != "" || *sheet != "" || *line != 0 {
+		mismatches = selectMismatches(mismatches, *file, *sheet, *line)
+		if len(mismatches) == 0 {
+			log.Fatalf("No mismatch recorded at %s", describeSelection(*file, *sheet, *line))
+		}
+	}
+
+	// 多个工作簿或工作表可能在同一行都有记录，-pairs 只能用于唯一的一条
+	if *pairs != "" && len(mismatches) > 1 {
+		fmt.Printf("%d records match %s:\n", len(mismatches), describeSelection(*file, *sheet, *line))
+		for _, m := range mismatches {
+			fmt.Printf("  %s[%s] row %d: %s\n", m.File, m.Sheet, m.Line, strings.Join(m.Codes, " "))
+		}
+		log.Fatal("-pairs needs a single record, narrow it down with -file and -sheet")
+	}
+
+	if *pairs != "" {
+		fixed, err := parsePairs(*pairs)
+		if err != nil {
+			log.Fatalf("Invalid -pairs: %v", err)
+		}
+		for _, m := range mismatches {
+			m.Fixed = fixed
+		}
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *edition != "" {
+		cfg.Edition = *edition
+	}
+
+	var repo *repository.OccupationRepository
+	if !*dryRun {
+		db, err := database.NewConnection(cfg.GetDSN())
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+
+		repo = repository.NewOccupationRepository(db, cfg.Edition)
+	}
+	mismatchService := service.NewMismatchService(repo)
+
+	nodes, err := mismatchService.BuildFixNodes(mismatches, *acceptSuggested)
+	if err != nil {
+		log.Fatalf("Failed to build corrected nodes: %v", err)
+	}
+	if len(nodes) == 0 {
+		fmt.Println("Nothing to apply")
+		return
+	}
+
+	if cfg.GBM.DeriveDetails {
+		for _, node := range nodes {
+			node.GBM = model.DeriveGBM(node.Seq)
+		}
+	}
+
+	if *dryRun {
+		for _, node := range nodes {
+			fmt.Printf("%-12s %-8s %s%s\n", node.Seq, node.GBM, node.Name, node.MarkerSuffix())
+		}
+		fmt.Printf("Dry run: %d records not saved\n", len(nodes))
+		return
+	}
+
+	if err := mismatchService.ApplyFixes(nodes); err != nil {
+		log.Fatalf("Failed to apply fixes: %v", err)
+	}
+}
+
+// selectMismatches 按工作簿、工作表和行号筛选记录，空值或 0 表示不限；工作簿可以写完整路径或文件名
+func selectMismatches(mismatches []*parser.Mismatch, file, sheet string, line int) []*parser.Mismatch {
+	var selected []*parser.Mismatch
+	for _, m := range mismatches {
+		if file != "" && m.File != file && filepath.Base(m.File) != file {
+			continue
+		}
+		if sheet != "" && m.Sheet != sheet {
+			continue
+		}
+		if line != 0 && m.Line != line {
+			continue
+		}
+		selected = append(selected, m)
+	}
+	return selected
+}
+
+// describeSelection 描述筛选条件，用于提示信息
+func describeSelection(file, sheet string, line int) string {
+	var parts []string
+	if file != "" {
+		parts = append(parts, "file "+file)
+	}
+	if sheet != "" {
+		parts = append(parts, "sheet "+sheet)
+	}
+	if line != 0 {
+		parts = append(parts, fmt.Sprintf("line %d", line))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parsePairs 解析 "代码=名称,代码=名称" 格式的配对
+func parsePairs(text string) ([]parser.Pairing, error) {
+	var result []parser.Pairing
+	for _, item := range strings.Split(text, ",") {
+		seq, name, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected CODE=NAME, got %q", item)
+		}
+		result = append(result, parser.Pairing{Seq: strings.TrimSpace(seq), Name: strings.TrimSpace(name)})
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func TestParsePairs(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"2-03-06-01=兽医,2-03-06-02=兽药技术人员", "[{2-03-06-01 兽医} {2-03-06-02 兽药技术人员}]", false},
+		{" 2-03-06-01 = 兽医 , 2-03-06-02=兽药技术人员 ", "[{2-03-06-01 兽医} {2-03-06-02 兽药技术人员}]", false},
+		{"2-03-06-01=兽医=兽药", "[{2-03-06-01 兽医=兽药}]", false},
+		{"2-03-06-01", "", true},
+		{"2-03-06-01=兽医,", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			pairs, err := parsePairs(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", pairs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range pairs {
+				got = append(got, fmt.Sprintf("{%s %s}", p.Seq, p.Name))
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("got %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectMismatches(t *testing.T) {
+	mismatches := []*parser.Mismatch{
+		{File: "data/vol1.xlsx", Sheet: "Sheet1", Line: 10},
+		{File: "data/vol1.xlsx", Sheet: "Sheet2", Line: 10},
+		{File: "data/vol2.xlsx", Sheet: "Sheet1", Line: 10},
+		{File: "data/vol2.xlsx", Sheet: "Sheet1", Line: 20},
+	}
+
+	tests := []struct {
+		file, sheet string
+		line        int
+		want        string
+	}{
+		{"", "", 10, "[0 1 2]"},
+		{"vol1.xlsx", "", 10, "[0 1]"},
+		{"data/vol1.xlsx", "Sheet2", 10, "[1]"},
+		{"vol2.xlsx", "", 0, "[2 3]"},
+		{"", "Sheet1", 20, "[3]"},
+		{"vol3.xlsx", "", 10, "[]"},
+	}
+
+	for _, tt := range tests {
+		var got []int
+		for _, m := range selectMismatches(mismatches, tt.file, tt.sheet, tt.line) {
+			for i := range mismatches {
+				if mismatches[i] == m {
+					got = append(got, i)
+				}
+			}
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("file=%q sheet=%q line=%d: got %s, want %s", tt.file, tt.sheet, tt.line, s, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/llmcache"
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func main() {
+	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
+	var dir = flag.String("dir", "", "Cache directory (overrides ai.cache.dir)")
+	var model = flag.String("model", "", "Only entries of this model")
+	var promptVersion = flag.String("prompt-version", "", "Only entries of this prompt version")
+	var stale = flag.Bool("stale", false, "Only entries whose prompt version differs from the current one ("+parser.PromptVersion+")")
+	var contains = flag.String("contains", "", "Only entries whose input contains this text")
+	var before = flag.String("before", "", "Only entries created before this date (2006-01-02)")
+	var list = flag.Bool("list", false, "List matching entries")
+	var show = flag.String("show", "", "Print the entry with this hash (or hash prefix)")
+	var invalidate = flag.Bool("invalidate", false, "Delete matching entries")
+	var all = flag.Bool("all", false, "Allow -invalidate without any filter")
+	var export = flag.String("export", "", "Export matching entries as JSONL to this path (- for stdout)")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if *dir != "" {
+		cfg.AI.Cache.Dir = *dir
+	}
+
+	cache, err := llmcache.Open(cfg.AI.Cache.Dir)
+	if err != nil {
+		log.Fatalf("Failed to open cache: %v", err)
+	}
+
+	filter := llmcache.Filter{Model: *model, PromptVersion: *promptVersion, Contains: *contains}
+	if *stale {
+		filter.NotPromptVersion = parser.PromptVersion
+	}
+	if *before != "" {
+		if filter.Before, err = time.ParseInLocation("2006-01-02", *before, time.Local); err != nil {
+			log.Fatalf("Invalid -before: %v", err)
+		}
+	}
+
+	if *invalidate {
+		if filter == (llmcache.Filter{}) && !*all {
+			log.Fatal("-invalidate without a filter deletes the whole cache, add -all to confirm")
+		}
+		removed, err := cache.Invalidate(filter)
+		if err != nil {
+			log.Fatalf("Failed to invalidate cache: %v", err)
+		}
+		fmt.Printf("Removed %d entries from %s\n", removed, cache.Dir())
+		return
+	}
+
+	entries, err := cache.List(filter)
+	if err != nil {
+		log.Fatalf("Failed to read cache: %v", err)
+	}
+
+	switch {
+	case *show != "":
+		showEntry(entries, *show)
+	case *export != "":
+		if err := exportEntries(entries, *export); err != nil {
+			log.Fatalf("Failed to export cache: %v", err)
+		}
+	case *list:
+		for _, e := range entries {
+			fmt.Printf("%s  %s  %-12s v%-3s %d names  %s\n", e.Hash[:12], e.CreatedAt.Format("2006-01-02 15:04"),
+				e.Model, e.PromptVersion, len(e.Names), abbreviate(e.Input, 40))
+		}
+		fmt.Printf("%d entries\n", len(entries))
+	default:
+		printStats(cache.Dir(), entries)
+	}
+}
+
+// printStats 按模型和提示词版本统计条目数
+func printStats(dir string, entries []*llmcache.Entry) {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[fmt.Sprintf("%s v%s t=%g", e.Model, e.PromptVersion, e.Temperature)]++
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Printf("Cache %s: %d entries (current prompt version %s)\n", dir, len(entries), parser.PromptVersion)
+	for _, k := range keys {
+		fmt.Printf("  %-40s %d\n", k, counts[k])
+	}
+}
+
+func showEntry(entries []*llmcache.Entry, hash string) {
+	var found []*llmcache.Entry
+	for _, e := range entries {
+		if strings.HasPrefix(e.Hash, hash) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		log.Fatalf("No entry matches %s", hash)
+	case 1:
+	default:
+		log.Fatalf("%d entries match %s, use a longer prefix", len(found), hash)
+	}
+
+	e := found[0]
+	fmt.Printf("Hash:           %s\nModel:          %s\nPrompt version: %s\nTemperature:    %g\nCreated:        %s\n",
+		e.Hash, e.Model, e.PromptVersion, e.Temperature, e.CreatedAt.Format(time.RFC3339))
+	if e.Codes != "" {
+		fmt.Printf("Codes:          %s\n", e.Codes)
+	}
+	fmt.Printf("Input:\n%s\nNames:\n", e.Input)
+	for i, name := range e.Names {
+		fmt.Printf("  %d. %s\n", i+1, name)
+	}
+}
+
+// exportEntries 把条目写成 JSONL，path 为 - 时写到标准输出
+func exportEntries(entries []*llmcache.Entry, path string) error {
+	if path == "-" {
+		return llmcache.WriteJSONL(os.Stdout, entries)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := llmcache.WriteJSONL(file, entries); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+
+	fmt.Printf("Exported %d entries to %s\n", len(entries), path)
+	return nil
+}
+
+func abbreviate(text string, limit int) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	if runes := []rune(text); len(runes) > limit {
+		return string(runes[:limit]) + "…"
+	}
+	return text
+}
@@ -4,25 +4,103 @@ import (
 	"flag"
 	"fmt"
 	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/corrections"
 	"github.com/solisamicus/occstructor/internal/parser"
 	"github.com/solisamicus/occstructor/internal/repository"
 	"github.com/solisamicus/occstructor/internal/service"
 	"github.com/solisamicus/occstructor/pkg/database"
 	"log"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 func main() {
 	var configPath = flag.String("config", "configs/config.yaml", "Path to config file")
-	var excelPath = flag.String("excel", "", "Path to excel file (overrides config)")
+	var edition = flag.String("edition", "", "Edition of the classification, e.g. 1999, 2015 or 2022 (overrides config)")
+	var excelPath = flag.String("excel", "", "Path to excel file, comma-separated for multiple volumes (overrides config)")
+	var correctionsPath = flag.String("corrections", "", "Path to corrections overlay file (overrides config)")
+	var reportPath = flag.String("report", "", "Parse report output path (default: logs/parse_report_TIMESTAMP.json)")
+	var dryRun = flag.Bool("dry-run", false, "Parse and export JSON without connecting to the database")
+	var output = flag.String("output", "", "Dry-run export path (default: exports/occupations_FORMAT_TIMESTAMP.json)")
+	var format = flag.String("format", "tree", "Dry-run export format: tree or flat")
+	var withProvenance = flag.Bool("with-provenance", false, "Include source sheet/row/column/raw text of each record in the dry-run export")
+	var debug = flag.Bool("debug", false, "Print every skipped row with the skip rule that matched (same as logging.level: debug)")
 	flag.Parse()
 
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if *edition != "" {
+		cfg.Edition = *edition
+	}
 
 	if *excelPath != "" {
-		cfg.Excel.Filepath = *excelPath
+		paths := strings.Split(*excelPath, ",")
+		cfg.Excel.Filepath = paths[0]
+		cfg.Excel.Filepaths = paths[1:]
+	}
+
+	if *correctionsPath != "" {
+		cfg.Corrections.Filepath = *correctionsPath
+	}
+
+	if *debug {
+		cfg.Logging.Level = "debug"
+	}
+
+	var overlay *corrections.Overlay
+	if cfg.Corrections.Filepath != "" {
+		overlay, err = corrections.Load(cfg.Corrections.Filepath)
+		if err != nil {
+			log.Fatalf("Failed to load corrections: %v", err)
+		}
+	}
+
+	mismatchLogger, err := parser.NewMismatchLogger()
+	if err != nil {
+		log.Fatalf("Failed to create mismatch logger: %v", err)
+	}
+	defer mismatchLogger.Close()
+
+	parser, err := parser.NewExcelParser(cfg, mismatchLogger)
+	if err != nil {
+		log.Fatalf("Failed to create parser: %v", err)
+	}
+
+	files := cfg.GetExcelFiles()
+	fmt.Printf("Parsing Excel files (edition %s): %s\n", cfg.Edition, strings.Join(files, ", "))
+
+	if *dryRun {
+		outputPath := *output
+		if outputPath == "" {
+			timestamp := time.Now().Format("20060102_150405")
+			outputPath = filepath.Join("exports", fmt.Sprintf("occupations_%s_%s_%s.json", cfg.Edition, *format, timestamp))
+		}
+		options := &service.ExportOptions{
+			OutputPath:     outputPath,
+			Format:         *format,
+			IncludeStats:   true,
+			WithProvenance: *withProvenance,
+		}
+
+		nodes, report, err := service.NewOccupationService(nil, parser, overlay).Parse(files...)
+		if err != nil {
+			log.Fatalf("Failed to parse: %v", err)
+		}
+		writeReport(report, *reportPath)
+
+		if err := service.ExportNodes(nodes, options); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+
+		if report.Validation.HasErrors() {
+			log.Fatalf("Dry run found %d validation errors", report.Validation.Errors)
+		}
+
+		fmt.Println("Dry run completed, database not touched")
+		return
 	}
 
 	db, err := database.NewConnection(cfg.GetDSN())
@@ -31,15 +109,28 @@ func main() {
 	}
 	defer db.Close()
 
-	repo := repository.NewOccupationRepository(db)
-	parser := parser.NewExcelParser(cfg)
-	service := service.NewOccupationService(repo, parser)
+	repo := repository.NewOccupationRepository(db, cfg.Edition)
+	service := service.NewOccupationService(repo, parser, overlay)
 
-	fmt.Printf("Parsing Excel file: %s\n", cfg.Excel.Filepath)
-	if err := service.ParseAndSave(cfg.Excel.Filepath); err != nil {
+	report, err := service.ParseAndSave(files...)
+	if report != nil {
+		writeReport(report, *reportPath)
+	}
+	if err != nil {
 		log.Fatalf("Failed to parse and save: %v", err)
 	}
-	parser.CloseLogger()
 
 	fmt.Println("Process completed successfully!")
 }
+
+// writeReport 写入解析报告，路径为空时使用带时间戳的默认路径
+func writeReport(report *parser.ParseReport, path string) {
+	if path == "" {
+		path = filepath.Join("logs", fmt.Sprintf("parse_report_%s.json", time.Now().Format("20060102_150405")))
+	}
+	if err := report.WriteJSON(path); err != nil {
+		log.Printf("Failed to write parse report: %v", err)
+	} else {
+		fmt.Printf("Parse report written to: %s\n", path)
+	}
+}
@@ -4,9 +4,17 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"strings"
+	"time"
 )
 
+// DefaultEdition 未配置版本时使用的大典版本
+const DefaultEdition = "2022"
+
 type Config struct {
+	// 大典版本，如 1999、2015、2022；不同版本的数据在数据库中共存
+	Edition string `yaml:"edition"`
+
 	Database struct {
 		Host     string `yaml:"host"`
 		Port     int    `yaml:"port"`
@@ -16,25 +24,132 @@ type Config struct {
 	} `yaml:"database"`
 
 	Excel struct {
-		Filepath string `yaml:"filepath"`
+		Filepath      string   `yaml:"filepath"`
+		Filepaths     []string `yaml:"filepaths"`      // 多个分卷文件，按顺序合并
+		IncludeSheets []string `yaml:"include_sheets"` // 工作表名通配符，为空时解析全部
+		ExcludeSheets []string `yaml:"exclude_sheets"`
+		// 职业描述(定义、主要工作任务、工种)所在工作表的通配符，这些工作表按描述格式解析
+		DescriptionSheets []string `yaml:"description_sheets"`
+		// 跳过表头、续表等非数据行的规则；配置后替换默认规则
+		SkipRules []SkipRule `yaml:"skip_rules"`
+
+		// 列布局，使用 Excel 列字母；未配置的字段使用默认布局 A/A/C/E/F
+		Columns struct {
+			AutoDetect bool   `yaml:"auto_detect"`
+			Major      string `yaml:"major"`
+			Middle     string `yaml:"middle"`
+			Minor      string `yaml:"minor"`
+			DetailCode string `yaml:"detail_code"`
+			DetailName string `yaml:"detail_name"`
+		} `yaml:"columns"`
 	} `yaml:"excel"`
 
 	AI struct {
+		// 名称合并方式：openai(默认)、rules、record(录制到 fixtures)、replay(从 fixtures 回放)
+		Provider    string  `yaml:"provider"`
+		Fixtures    string  `yaml:"fixtures"` // record/replay 使用的 JSONL 文件
 		APIKeyEnv   string  `yaml:"api_key_env"`
 		BaseURL     string  `yaml:"base_url"`
 		Model       string  `yaml:"model"`
 		Temperature float64 `yaml:"temperature"`
 		Enabled     bool    `yaml:"enabled"`
+		// 名称数量与代码不符或结果无法解析时，把错误反馈给模型重新生成的最多次数
+		Reprompts int `yaml:"reprompts"`
+		// 要求接口按格式输出：json_schema(默认)、json_object、off；接口不支持时自动降级
+		StructuredOutput string `yaml:"structured_output"`
+
+		Concurrency       int           `yaml:"concurrency"`         // 同时处理的名称单元格数
+		RequestsPerSecond float64       `yaml:"requests_per_second"` // 每秒请求数上限，0 表示不限
+		Timeout           time.Duration `yaml:"timeout"`             // 单次请求超时，如 60s
+		MaxRetries        int           `yaml:"max_retries"`         // 429、5xx、超时后的最多重试次数
+		RetryBackoff      time.Duration `yaml:"retry_backoff"`       // 首次重试的等待时间，之后每次翻倍
+
+		// 大模型结果的磁盘缓存，按模型、提示词版本、温度和清洗后的输入命中
+		Cache struct {
+			Enabled bool   `yaml:"enabled"`
+			Dir     string `yaml:"dir"`
+			Offline bool   `yaml:"offline"` // 未命中时不调用接口，直接使用规则处理
+		} `yaml:"cache"`
 	}
 
+	// 人工修正文件，每次导入时应用
+	Corrections struct {
+		Filepath string `yaml:"filepath"`
+	} `yaml:"corrections"`
+
+	// 职业名称清洗规则
+	Normalize struct {
+		Codes        bool     `yaml:"codes"`         // 匹配编码前在编码上下文中做 NFKC 及 OCR 易混字符修正
+		FoldWidth    bool     `yaml:"fold_width"`    // 全角字母数字转半角，括号统一为全角
+		Punctuation  string   `yaml:"punctuation"`   // 保留的标点
+		KeepLatin    bool     `yaml:"keep_latin"`    // 保留拉丁字母，如 CAD
+		KeepDigits   bool     `yaml:"keep_digits"`   // 保留数字，如 3D、5G
+		JunkPatterns []string `yaml:"junk_patterns"` // 清洗前删除的 OCR 噪声正则
+	} `yaml:"normalize"`
+
+	// GBM 编码规则
+	GBM struct {
+		DeriveDetails bool `yaml:"derive_details"` // 为细类按编号推导 GBM
+	} `yaml:"gbm"`
+
 	Logging struct {
-		Level string `yaml:"level"`
+		Level string `yaml:"level"` // debug 时打印每个被跳过的行及命中的规则
 	} `yaml:"logging"`
 }
 
-func LoadConfig(filepath string) (*Config, error) {
+// 跳过规则类型
+const (
+	SkipExact    = "exact"    // 单元格去掉空白后与 pattern 完全相同
+	SkipContains = "contains" // 单元格包含 pattern
+	SkipRegex    = "regex"    // 单元格匹配正则 pattern
+	SkipRow      = "row"      // 跳过 rows 中列出的行，不看内容
+)
+
+// SkipRule 一条跳过规则，任一单元格命中即跳过整行
+type SkipRule struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`
+	Pattern string `yaml:"pattern"`
+	Column  string `yaml:"column"` // 只检查该列(Excel 列字母)，为空时检查所有列
+	Rows    []int  `yaml:"rows"`   // 行号(从 1 开始)；row 规则跳过这些行，其他规则只在这些行生效
+}
+
+// DefaultSkipRules 默认跳过规则：分类体系表标题、页眉、列标题行和续表标记。
+// 与早期写死的规则相比只收窄了列标题 "中类"(改为完全匹配)，其余仍按包含匹配
+func DefaultSkipRules() []SkipRule {
+	return []SkipRule{
+		{Name: "title", Type: SkipContains, Pattern: "分类体系表"},
+		{Name: "page_header", Type: SkipRegex, Pattern: `中华人民共和国|职\s*业\s*分\s*类\s*大\s*典`},
+		{Name: "column_header", Type: SkipExact, Pattern: "中类"},
+		{Name: "continuation", Type: SkipContains, Pattern: "续表"},
+	}
+}
+
+// DefaultConfig 返回默认配置，配置文件中未出现的字段保留这里的值
+func DefaultConfig() *Config {
 	config := &Config{}
 
+	config.Edition = DefaultEdition
+	config.Excel.SkipRules = DefaultSkipRules()
+	config.AI.Reprompts = 2
+	config.AI.StructuredOutput = "json_schema"
+	config.AI.Concurrency = 4
+	config.AI.Timeout = 60 * time.Second
+	config.AI.MaxRetries = 3
+	config.AI.RetryBackoff = time.Second
+	config.AI.Cache.Dir = "cache/llm"
+	config.Normalize.Codes = true
+	config.Normalize.FoldWidth = true
+	config.Normalize.Punctuation = "、（）《》·—"
+	config.Normalize.KeepLatin = true
+	config.Normalize.KeepDigits = true
+
+	return config
+}
+
+func LoadConfig(filepath string) (*Config, error) {
+	config := DefaultConfig()
+
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open config file: %w", err)
@@ -62,3 +177,20 @@ func (c *Config) GetDSN() string {
 func (c *Config) GetAPIKey() string {
 	return os.Getenv(c.AI.APIKeyEnv)
 }
+
+// GetExcelFiles 返回需要解析的全部 Excel 文件，filepath 在前，filepaths 依次追加
+func (c *Config) GetExcelFiles() []string {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, f := range append([]string{c.Excel.Filepath}, c.Excel.Filepaths...) {
+		f = strings.TrimSpace(f)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		files = append(files, f)
+	}
+
+	return files
+}
@@ -0,0 +1,260 @@
+package corrections
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/solisamicus/occstructor/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion 当前支持的修正文件版本
+const CurrentVersion = 1
+
+type Op string
+
+const (
+	OpRename   Op = "rename"
+	OpAdd      Op = "add"
+	OpDelete   Op = "delete"
+	OpReparent Op = "reparent"
+	OpSetGBM   Op = "set_gbm"
+)
+
+// Correction 一条人工修正，按编号定位节点
+type Correction struct {
+	Op        Op     `yaml:"op" json:"op"`
+	Seq       string `yaml:"seq" json:"seq"`
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"`
+	GBM       string `yaml:"gbm,omitempty" json:"gbm,omitempty"`
+	ParentSeq string `yaml:"parent_seq,omitempty" json:"parent_seq,omitempty"`
+	Level     int    `yaml:"level,omitempty" json:"level,omitempty"`
+	Green     bool   `yaml:"green,omitempty" json:"green,omitempty"`
+	Digital   bool   `yaml:"digital,omitempty" json:"digital,omitempty"`
+	Note      string `yaml:"note,omitempty" json:"note,omitempty"`
+}
+
+// Overlay 修正文件，每次导入时在 BuildHierarchy 之后、入库之前应用
+type Overlay struct {
+	Version     int          `yaml:"version" json:"version"`
+	Corrections []Correction `yaml:"corrections" json:"corrections"`
+
+	path string
+}
+
+// Load 读取 YAML 或 JSON 格式的修正文件
+func Load(path string) (*Overlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corrections file: %w", err)
+	}
+
+	overlay := &Overlay{path: path}
+	if err := yaml.Unmarshal(data, overlay); err != nil {
+		return nil, fmt.Errorf("failed to decode corrections file: %w", err)
+	}
+
+	if overlay.Version != CurrentVersion {
+		return nil, fmt.Errorf("unsupported corrections version %d (want %d)", overlay.Version, CurrentVersion)
+	}
+
+	for i, c := range overlay.Corrections {
+		if err := c.validate(); err != nil {
+			return nil, fmt.Errorf("correction #%d (%s %s): %w", i+1, c.Op, c.Seq, err)
+		}
+	}
+
+	return overlay, nil
+}
+
+func (c *Correction) validate() error {
+	if c.Seq == "" {
+		return fmt.Errorf("seq is required")
+	}
+
+	switch c.Op {
+	case OpRename, OpAdd:
+		if c.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+	case OpReparent:
+		if c.ParentSeq == "" {
+			return fmt.Errorf("parent_seq is required")
+		}
+	case OpSetGBM:
+		if c.GBM == "" {
+			return fmt.Errorf("gbm is required")
+		}
+	case OpDelete:
+	default:
+		return fmt.Errorf("unknown op %q", c.Op)
+	}
+
+	return nil
+}
+
+// DeletedSeqs 返回所有删除修正的编号，无论本次解析结果中是否存在，数据库中都需要删除
+func (o *Overlay) DeletedSeqs() []string {
+	var seqs []string
+	for _, c := range o.Corrections {
+		if c.Op == OpDelete {
+			seqs = append(seqs, c.Seq)
+		}
+	}
+	return seqs
+}
+
+// Apply 按文件顺序应用修正，返回修正后的节点和命中/仅数据库/过期报告
+func (o *Overlay) Apply(nodes []*model.OccupationNode) ([]*model.OccupationNode, *Report) {
+	report := &Report{
+		File:         o.path,
+		Version:      o.Version,
+		Applied:      []Result{},
+		DatabaseOnly: []Result{},
+		Stale:        []Result{},
+	}
+
+	index := make(map[string]*model.OccupationNode, len(nodes))
+	for _, node := range nodes {
+		index[node.Seq] = node
+	}
+
+	var added []*model.OccupationNode
+	removed := make(map[*model.OccupationNode]bool)
+
+	for _, c := range o.Corrections {
+		node, exists := index[c.Seq]
+
+		stale := func(reason string) {
+			report.Stale = append(report.Stale, Result{Correction: c, Reason: reason})
+		}
+
+		// 本次解析中没有的节点仍会从数据库删除，不算过期
+		if c.Op == OpDelete && !exists {
+			report.DatabaseOnly = append(report.DatabaseOnly, Result{Correction: c, Reason: "seq not in parse result"})
+			continue
+		}
+
+		if c.Op != OpAdd && !exists {
+			stale("seq not found")
+			continue
+		}
+
+		switch c.Op {
+		case OpRename:
+			if node.Name == c.Name {
+				stale("name already matches")
+				continue
+			}
+			node.Name = c.Name
+
+		case OpAdd:
+			if exists {
+				stale(fmt.Sprintf("seq already exists as %q", node.Name))
+				continue
+			}
+			node = c.newNode()
+			if node.ParentSeq != nil {
+				if parent, ok := index[*node.ParentSeq]; ok {
+					node.Edition = parent.Edition
+				}
+			}
+			index[c.Seq] = node
+			added = append(added, node)
+
+		case OpDelete:
+			// 子孙节点一并删除，与数据库中的级联删除保持一致
+			descendants := descendantsOf(index, c.Seq)
+			for _, d := range append(descendants, node) {
+				removed[d] = true
+				delete(index, d.Seq)
+			}
+			result := Result{Correction: c}
+			for _, d := range descendants {
+				result.Cascaded = append(result.Cascaded, d.Seq)
+			}
+			report.Applied = append(report.Applied, result)
+			continue
+
+		case OpReparent:
+			if node.ParentSeq != nil && *node.ParentSeq == c.ParentSeq {
+				stale("parent already matches")
+				continue
+			}
+			if _, ok := index[c.ParentSeq]; !ok {
+				stale(fmt.Sprintf("parent %s not found", c.ParentSeq))
+				continue
+			}
+			parentSeq := c.ParentSeq
+			node.ParentSeq = &parentSeq
+
+		case OpSetGBM:
+			if node.GBM == c.GBM {
+				stale("gbm already matches")
+				continue
+			}
+			node.GBM = c.GBM
+		}
+
+		report.Applied = append(report.Applied, Result{Correction: c})
+	}
+
+	var result []*model.OccupationNode
+	for _, node := range append(nodes, added...) {
+		if !removed[node] {
+			result = append(result, node)
+		}
+	}
+
+	return result, report
+}
+
+// descendantsOf 按上级编号查找 seq 的全部子孙节点，按层级由浅到深返回
+func descendantsOf(index map[string]*model.OccupationNode, seq string) []*model.OccupationNode {
+	var descendants []*model.OccupationNode
+	parents := map[string]bool{seq: true}
+
+	for len(parents) > 0 {
+		next := make(map[string]bool)
+		for _, node := range index {
+			if node.ParentSeq != nil && parents[*node.ParentSeq] {
+				descendants = append(descendants, node)
+				next[node.Seq] = true
+			}
+		}
+		parents = next
+	}
+
+	sort.SliceStable(descendants, func(i, j int) bool {
+		return descendants[i].Level < descendants[j].Level ||
+			descendants[i].Level == descendants[j].Level && descendants[i].Seq < descendants[j].Seq
+	})
+	return descendants
+}
+
+func (c *Correction) newNode() *model.OccupationNode {
+	level := c.Level
+	if level == 0 {
+		level = model.LevelOf(c.Seq)
+	}
+
+	parentSeq := c.ParentSeq
+	if parentSeq == "" {
+		parentSeq = model.GetParentSeq(c.Seq)
+	}
+
+	node := &model.OccupationNode{
+		Seq:     c.Seq,
+		GBM:     c.GBM,
+		Name:    c.Name,
+		Level:   level,
+		Green:   c.Green,
+		Digital: c.Digital,
+	}
+	if parentSeq != "" {
+		node.ParentSeq = &parentSeq
+	}
+
+	return node
+}
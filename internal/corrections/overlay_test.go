@@ -0,0 +1,137 @@
+package corrections_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/corrections"
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+func TestApply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrections.yaml")
+	err := os.WriteFile(path, []byte(`version: 1
+corrections:
+  - {op: rename, seq: "1-01", name: "中国共产党机关和基层组织负责人"}
+  - {op: rename, seq: "1-01-00-01", name: "中国共产党机关负责人"}
+  - {op: add, seq: "1-01-00-03", name: "新增职业", green: true}
+  - {op: delete, seq: "1-01-00-02"}
+  - {op: set_gbm, seq: "1-01-00", gbm: "10100"}
+  - {op: reparent, seq: "1-01-00-01", parent_seq: "1-99"}
+  - {op: rename, seq: "9-99", name: "不存在"}
+  - {op: delete, seq: "1-02"}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, err := corrections.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &model.ParseResult{
+		Majors:    []*model.OccupationNode{{Seq: "1", GBM: "10", Name: "负责人", Level: 1}},
+		Middles:   []*model.OccupationNode{{Seq: "1-01", GBM: "10100", Name: "中国共产党机关和基层组织负责人", Level: 2}},
+		Minors:    []*model.OccupationNode{{Seq: "1-01-00", Name: "中国共产党机关和基层组织负责人", Level: 3}},
+		SubMinors: []*model.OccupationNode{{Seq: "1-01-00-01", Name: "中国共产党机关负", Level: 4}, {Seq: "1-01-00-02", Name: "重复", Level: 4}},
+	}
+
+	nodes, report := overlay.Apply(result.BuildHierarchy())
+
+	bySeq := make(map[string]*model.OccupationNode)
+	for _, node := range nodes {
+		bySeq[node.Seq] = node
+	}
+	if _, ok := bySeq["1-01-00-02"]; ok {
+		t.Error("deleted node is still present")
+	}
+	if got := bySeq["1-01-00-01"].Name; got != "中国共产党机关负责人" {
+		t.Errorf("got name %q after rename", got)
+	}
+	if added := bySeq["1-01-00-03"]; added == nil || added.Level != 4 || *added.ParentSeq != "1-01-00" || !added.Green {
+		t.Errorf("got added node %+v", added)
+	}
+	if got := bySeq["1-01-00"].GBM; got != "10100" {
+		t.Errorf("got gbm %q", got)
+	}
+
+	if len(report.Applied) != 4 || len(report.Stale) != 3 {
+		t.Fatalf("got %d applied, %d stale: %+v", len(report.Applied), len(report.Stale), report.Stale)
+	}
+	wantStale := []string{"name already matches", "parent 1-99 not found", "seq not found"}
+	for i, s := range report.Stale {
+		if s.Reason != wantStale[i] {
+			t.Errorf("stale #%d: got %q, want %q", i, s.Reason, wantStale[i])
+		}
+	}
+	// 解析结果中没有的删除仍会作用于数据库，单独报告而不是记为过期
+	if len(report.DatabaseOnly) != 1 || report.DatabaseOnly[0].Seq != "1-02" {
+		t.Errorf("got database only %+v", report.DatabaseOnly)
+	}
+	if seqs := overlay.DeletedSeqs(); fmt.Sprint(seqs) != "[1-01-00-02 1-02]" {
+		t.Errorf("got deleted seqs %v", seqs)
+	}
+}
+
+func TestLoadRejectsUnknownVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrections.json")
+	if err := os.WriteFile(path, []byte(`{"version": 2, "corrections": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := corrections.Load(path); err == nil {
+		t.Error("expected an error for version 2")
+	}
+}
+
+func TestApplyDeleteWithChildren(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrections.yaml")
+	err := os.WriteFile(path, []byte(`version: 1
+corrections:
+  - {op: reparent, seq: "1-01-01-01", parent_seq: "1-01-00"}
+  - {op: delete, seq: "1-01-01"}
+  - {op: rename, seq: "1-01-01-02", name: "已随上级删除"}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay, err := corrections.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &model.ParseResult{
+		Majors:    []*model.OccupationNode{{Seq: "1", Name: "负责人", Level: 1}},
+		Middles:   []*model.OccupationNode{{Seq: "1-01", Name: "中国共产党机关和基层组织负责人", Level: 2}},
+		Minors:    []*model.OccupationNode{{Seq: "1-01-00", Name: "中国共产党机关和基层组织负责人", Level: 3}, {Seq: "1-01-01", Name: "重复小类", Level: 3}},
+		SubMinors: []*model.OccupationNode{{Seq: "1-01-01-01", Name: "保留", Level: 4}, {Seq: "1-01-01-02", Name: "删除", Level: 4}},
+		SubTypes:  []*model.OccupationNode{{Seq: "1-01-01-02-01", Name: "删除的工种", Level: 5}},
+	}
+
+	nodes, report := overlay.Apply(result.BuildHierarchy())
+
+	var seqs []string
+	bySeq := make(map[string]bool)
+	for _, node := range nodes {
+		seqs = append(seqs, node.Seq)
+		bySeq[node.Seq] = true
+	}
+	// 被删除节点的子孙不能以悬空的上级编号留在结果中
+	for _, node := range nodes {
+		if node.ParentSeq != nil && !bySeq[*node.ParentSeq] {
+			t.Errorf("%s keeps missing parent %s", node.Seq, *node.ParentSeq)
+		}
+	}
+	if fmt.Sprint(seqs) != "[1 1-01 1-01-00 1-01-01-01]" {
+		t.Errorf("got %v", seqs)
+	}
+
+	if len(report.Applied) != 2 || len(report.Stale) != 1 || report.Stale[0].Reason != "seq not found" {
+		t.Fatalf("got applied %+v, stale %+v", report.Applied, report.Stale)
+	}
+	if got := report.Applied[1].Cascaded; fmt.Sprint(got) != "[1-01-01-02 1-01-01-02-01]" {
+		t.Errorf("got cascaded %v", got)
+	}
+}
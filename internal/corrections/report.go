@@ -0,0 +1,37 @@
+package corrections
+
+import "fmt"
+
+// Result 一条修正的应用结果，Reason 说明过期原因
+type Result struct {
+	Correction
+	Reason string `json:"reason,omitempty"`
+	// Cascaded 删除节点时一并删除的子孙节点编号
+	Cascaded []string `json:"cascaded,omitempty"`
+}
+
+// Report 修正文件的应用报告
+type Report struct {
+	File    string   `json:"file"`
+	Version int      `json:"version"`
+	Applied []Result `json:"applied"`
+	// DatabaseOnly 编号不在本次解析结果中的删除修正，入库时仍从数据库删除
+	DatabaseOnly []Result `json:"database_only"`
+	Stale        []Result `json:"stale"`
+}
+
+func (r *Report) Print() {
+	fmt.Printf("Corrections %s (version %d): %d applied, %d applied (database only), %d stale\n",
+		r.File, r.Version, len(r.Applied), len(r.DatabaseOnly), len(r.Stale))
+	for _, a := range r.Applied {
+		if len(a.Cascaded) > 0 {
+			fmt.Printf("  Deleted: %-12s with %d descendants\n", a.Seq, len(a.Cascaded))
+		}
+	}
+	for _, d := range r.DatabaseOnly {
+		fmt.Printf("  Database only: %-8s %-12s %s\n", d.Op, d.Seq, d.Reason)
+	}
+	for _, s := range r.Stale {
+		fmt.Printf("  Stale: %-8s %-12s %s\n", s.Op, s.Seq, s.Reason)
+	}
+}
@@ -0,0 +1,170 @@
+package crosswalk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// SystemISCO08 国际标准职业分类 ISCO-08，其余目标体系均为大典版本号
+const SystemISCO08 = "isco08"
+
+const (
+	RelationExact    = "exact"
+	RelationBroader  = "broader"  // 目标比源更宽
+	RelationNarrower = "narrower" // 目标比源更窄
+	RelationPartial  = "partial"
+)
+
+var relations = map[string]string{
+	RelationExact:    RelationExact,
+	RelationBroader:  RelationNarrower,
+	RelationNarrower: RelationBroader,
+	RelationPartial:  RelationPartial,
+}
+
+var (
+	iscoRegex    = regexp.MustCompile(`^\d{1,4}$`)
+	editionRegex = regexp.MustCompile(`^\d{4}$`)
+)
+
+// Entry 对照表中的一行，源与目标之间为多对多关系
+type Entry struct {
+	SourceEdition string `json:"source_edition"`
+	SourceSeq     string `json:"source_seq"`
+	TargetSystem  string `json:"target_system"`
+	TargetCode    string `json:"target_code"`
+	Relation      string `json:"relation"`
+	Note          string `json:"note,omitempty"`
+}
+
+// IsEdition 目标体系是否为大典版本
+func (e *Entry) IsEdition() bool {
+	return e.TargetSystem != SystemISCO08
+}
+
+// InverseRelation 返回反向映射的关系，如 broader 的反向为 narrower
+func InverseRelation(relation string) string {
+	return relations[relation]
+}
+
+func (e *Entry) validate() error {
+	if e.SourceEdition == "" || e.SourceSeq == "" || e.TargetSystem == "" || e.TargetCode == "" {
+		return errors.New("source_edition, source_seq, target_system and target_code are required")
+	}
+	if _, ok := relations[e.Relation]; !ok {
+		return fmt.Errorf("unknown relation %q", e.Relation)
+	}
+
+	switch {
+	case e.TargetSystem == SystemISCO08:
+		if !iscoRegex.MatchString(e.TargetCode) {
+			return fmt.Errorf("invalid ISCO-08 code %q", e.TargetCode)
+		}
+	case editionRegex.MatchString(e.TargetSystem):
+		if e.TargetSystem == e.SourceEdition {
+			return fmt.Errorf("target edition equals source edition %s", e.SourceEdition)
+		}
+	default:
+		return fmt.Errorf("unknown target system %q (want %s or an edition such as 2015)", e.TargetSystem, SystemISCO08)
+	}
+
+	return nil
+}
+
+var columns = []string{"source_edition", "source_seq", "target_system", "target_code", "relation", "note"}
+
+// ReadCSVFile 读取对照表 CSV，表头须包含 source_seq、target_code 等列(顺序不限)。
+// 缺少 source_edition、target_system 或 relation 列时使用 defaults 中的值
+func ReadCSVFile(path string, defaults Entry) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open crosswalk file: %w", err)
+	}
+	defer f.Close()
+
+	return ReadCSV(f, defaults)
+}
+
+func ReadCSV(r io.Reader, defaults Entry) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	index := make(map[string]int)
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"source_seq", "target_code"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("missing column %q, header must contain %s", required, strings.Join(columns, ","))
+		}
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(name, fallback string) string {
+			if i, ok := index[name]; ok && i < len(record) {
+				if v := strings.TrimSpace(record[i]); v != "" {
+					return v
+				}
+			}
+			return fallback
+		}
+
+		entry := Entry{
+			SourceEdition: field("source_edition", defaults.SourceEdition),
+			SourceSeq:     field("source_seq", ""),
+			TargetSystem:  strings.ToLower(field("target_system", defaults.TargetSystem)),
+			TargetCode:    field("target_code", ""),
+			Relation:      strings.ToLower(field("relation", defaults.Relation)),
+			Note:          field("note", ""),
+		}
+		if entry.SourceSeq == "" && entry.TargetCode == "" {
+			continue
+		}
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Problem 对照表中引用了不存在编号的条目
+type Problem struct {
+	Entry
+	Reason string `json:"reason"`
+}
+
+// Validate 检查源编号及大典版本间映射的目标编号均存在，exists 按版本和编号查询 occupations
+func Validate(entries []Entry, exists func(edition, seq string) bool) []Problem {
+	var problems []Problem
+	for _, e := range entries {
+		if !exists(e.SourceEdition, e.SourceSeq) {
+			problems = append(problems, Problem{Entry: e, Reason: fmt.Sprintf("source seq %s not found in edition %s", e.SourceSeq, e.SourceEdition)})
+		}
+		if e.IsEdition() && !exists(e.TargetSystem, e.TargetCode) {
+			problems = append(problems, Problem{Entry: e, Reason: fmt.Sprintf("target seq %s not found in edition %s", e.TargetCode, e.TargetSystem)})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,63 @@
+package crosswalk_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/crosswalk"
+)
+
+func TestReadCSV(t *testing.T) {
+	input := "\ufeffsource_seq,target_code,relation,note\n" +
+		"2-02-10-03,2512,exact,\n" +
+		"2-02-10-04, 2523 ,BROADER,网络\n" +
+		",,,\n"
+
+	entries, err := crosswalk.ReadCSV(strings.NewReader(input), crosswalk.Entry{
+		SourceEdition: "2022",
+		TargetSystem:  crosswalk.SystemISCO08,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[1]; e.SourceEdition != "2022" || e.TargetCode != "2523" || e.Relation != crosswalk.RelationBroader || e.Note != "网络" {
+		t.Errorf("got %+v", e)
+	}
+
+	bad := []string{
+		"source_seq,target_code,relation\n2-02-10-03,25120,exact\n",
+		"source_seq,target_code,relation\n2-02-10-03,2512,similar\n",
+		"source_seq,target_system,target_code\n2-02-10-03,onet,15-1252\n",
+		"seq,code\n2-02-10-03,2512\n",
+	}
+	for _, in := range bad {
+		if _, err := crosswalk.ReadCSV(strings.NewReader(in), crosswalk.Entry{SourceEdition: "2022", TargetSystem: crosswalk.SystemISCO08, Relation: "exact"}); err == nil {
+			t.Errorf("expected an error for %q", in)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	entries := []crosswalk.Entry{
+		{SourceEdition: "2015", SourceSeq: "2-02-10-03", TargetSystem: "2022", TargetCode: "2-02-10-03", Relation: "exact"},
+		{SourceEdition: "2015", SourceSeq: "2-02-10-09", TargetSystem: "2022", TargetCode: "2-02-10-99", Relation: "partial"},
+		{SourceEdition: "2022", SourceSeq: "2-02-10-03", TargetSystem: crosswalk.SystemISCO08, TargetCode: "2512", Relation: "exact"},
+	}
+	existing := map[string]bool{"2015/2-02-10-03": true, "2022/2-02-10-03": true}
+
+	problems := crosswalk.Validate(entries, func(edition, seq string) bool {
+		return existing[edition+"/"+seq]
+	})
+	if len(problems) != 2 {
+		t.Fatalf("got %d problems, want source and target of the second entry: %+v", len(problems), problems)
+	}
+	if problems[0].SourceSeq != "2-02-10-09" || !strings.HasPrefix(problems[1].Reason, "target seq 2-02-10-99") {
+		t.Errorf("got %+v", problems)
+	}
+	if crosswalk.InverseRelation(crosswalk.RelationBroader) != crosswalk.RelationNarrower {
+		t.Error("broader should invert to narrower")
+	}
+}
@@ -0,0 +1,246 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+type Kind string
+
+const (
+	KindAdded   Kind = "added"
+	KindRemoved Kind = "removed"
+	KindRenamed Kind = "renamed"
+	KindMoved   Kind = "moved"   // 上级编号变化
+	KindRecoded Kind = "recoded" // 编号或 GBM 变化
+)
+
+// Kinds 报告中各类变化的输出顺序
+var Kinds = []Kind{KindAdded, KindRemoved, KindRenamed, KindMoved, KindRecoded}
+
+// DefaultThreshold 名称相似度阈值，同编号名称相似度低于阈值视为删除后新增，不同编号高于阈值视为同一职业
+const DefaultThreshold = 0.6
+
+// Change 一条变化，Old* 字段为旧版本中的值
+type Change struct {
+	Kind         Kind    `json:"kind"`
+	Level        int     `json:"level"`
+	Seq          string  `json:"seq,omitempty"`
+	OldSeq       string  `json:"old_seq,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	OldName      string  `json:"old_name,omitempty"`
+	ParentSeq    string  `json:"parent_seq,omitempty"`
+	OldParentSeq string  `json:"old_parent_seq,omitempty"`
+	GBM          string  `json:"gbm,omitempty"`
+	OldGBM       string  `json:"old_gbm,omitempty"`
+	Similarity   float64 `json:"similarity,omitempty"`
+}
+
+type Options struct {
+	Threshold float64
+}
+
+// Compare 比较两组节点：先按编号配对，再在同层级剩余节点中按名称(完全相同优先，其次相似度)配对，
+// 仍未配对的记为新增或删除。配对后的节点再比较上级与 GBM，上级按配对后的编号比较，
+// 因此随上级一起改号的节点不算移动，改号同时换了上级的节点既记为 recoded 也记为 moved
+func Compare(before, after []*model.OccupationNode, opts Options) []Change {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+
+	oldBySeq := indexBySeq(before)
+	newBySeq := indexBySeq(after)
+
+	var changes []Change
+	var pairs [][2]*model.OccupationNode
+	var oldRest, newRest []*model.OccupationNode
+
+	for _, o := range before {
+		n, ok := newBySeq[o.Seq]
+		if !ok {
+			oldRest = append(oldRest, o)
+			continue
+		}
+		if o.Name != n.Name {
+			similarity := Similarity(o.Name, n.Name)
+			if similarity < opts.Threshold {
+				// 编号被复用于另一个职业
+				oldRest = append(oldRest, o)
+				newRest = append(newRest, n)
+				continue
+			}
+			changes = append(changes, newChange(KindRenamed, o, n, similarity))
+		}
+		pairs = append(pairs, [2]*model.OccupationNode{o, n})
+	}
+	for _, n := range after {
+		if _, ok := oldBySeq[n.Seq]; !ok {
+			newRest = append(newRest, n)
+		}
+	}
+
+	matched, oldRest, newRest := matchByName(oldRest, newRest, opts.Threshold)
+	for _, pair := range matched {
+		if o, n := pair[0], pair[1]; o.Name != n.Name {
+			changes = append(changes, newChange(KindRenamed, o, n, Similarity(o.Name, n.Name)))
+		}
+	}
+	pairs = append(pairs, matched...)
+
+	// 旧编号 → 新编号，用于判断上级是否变化
+	seqMap := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		seqMap[pair[0].Seq] = pair[1].Seq
+	}
+	for _, pair := range pairs {
+		changes = append(changes, compareAttributes(pair[0], pair[1], seqMap)...)
+	}
+
+	for _, o := range oldRest {
+		changes = append(changes, newChange(KindRemoved, o, nil, 0))
+	}
+	for _, n := range newRest {
+		changes = append(changes, newChange(KindAdded, nil, n, 0))
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// compareAttributes 比较一对节点的上级与编号/GBM，旧上级先按 seqMap 换成新编号再比较
+func compareAttributes(o, n *model.OccupationNode, seqMap map[string]string) []Change {
+	similarity := 0.0
+	if o.Name != n.Name {
+		similarity = Similarity(o.Name, n.Name)
+	}
+
+	var changes []Change
+	oldParent := parentOf(o)
+	if mapped, ok := seqMap[oldParent]; ok {
+		oldParent = mapped
+	}
+	if oldParent != parentOf(n) {
+		changes = append(changes, newChange(KindMoved, o, n, similarity))
+	}
+	if o.Seq != n.Seq || o.GBM != n.GBM && o.GBM != "" && n.GBM != "" {
+		changes = append(changes, newChange(KindRecoded, o, n, similarity))
+	}
+	return changes
+}
+
+// matchByName 在同层级未配对节点中配对：名称完全相同的先配对，其余按相似度从高到低贪心配对
+func matchByName(before, after []*model.OccupationNode, threshold float64) ([][2]*model.OccupationNode, []*model.OccupationNode, []*model.OccupationNode) {
+	var matched [][2]*model.OccupationNode
+	usedOld := make(map[*model.OccupationNode]bool)
+	usedNew := make(map[*model.OccupationNode]bool)
+
+	byName := make(map[string][]*model.OccupationNode)
+	for _, n := range after {
+		byName[nameKey(n)] = append(byName[nameKey(n)], n)
+	}
+	for _, o := range before {
+		candidates := byName[nameKey(o)]
+		if len(candidates) == 0 {
+			continue
+		}
+		matched = append(matched, [2]*model.OccupationNode{o, candidates[0]})
+		usedOld[o], usedNew[candidates[0]] = true, true
+		byName[nameKey(o)] = candidates[1:]
+	}
+
+	type candidate struct {
+		o, n       *model.OccupationNode
+		similarity float64
+	}
+	var candidates []candidate
+	for _, o := range before {
+		if usedOld[o] {
+			continue
+		}
+		for _, n := range after {
+			if usedNew[n] || n.Level != o.Level {
+				continue
+			}
+			if similarity := Similarity(o.Name, n.Name); similarity >= threshold {
+				candidates = append(candidates, candidate{o, n, similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	for _, c := range candidates {
+		if usedOld[c.o] || usedNew[c.n] {
+			continue
+		}
+		matched = append(matched, [2]*model.OccupationNode{c.o, c.n})
+		usedOld[c.o], usedNew[c.n] = true, true
+	}
+
+	var oldRest, newRest []*model.OccupationNode
+	for _, o := range before {
+		if !usedOld[o] {
+			oldRest = append(oldRest, o)
+		}
+	}
+	for _, n := range after {
+		if !usedNew[n] {
+			newRest = append(newRest, n)
+		}
+	}
+	return matched, oldRest, newRest
+}
+
+func newChange(kind Kind, o, n *model.OccupationNode, similarity float64) Change {
+	c := Change{Kind: kind, Similarity: similarity}
+	if o != nil {
+		c.Level = o.Level
+		c.OldSeq, c.OldName, c.OldParentSeq, c.OldGBM = o.Seq, o.Name, parentOf(o), o.GBM
+	}
+	if n != nil {
+		c.Level = n.Level
+		c.Seq, c.Name, c.ParentSeq, c.GBM = n.Seq, n.Name, parentOf(n), n.GBM
+	}
+	return c
+}
+
+func indexBySeq(nodes []*model.OccupationNode) map[string]*model.OccupationNode {
+	index := make(map[string]*model.OccupationNode, len(nodes))
+	for _, node := range nodes {
+		index[node.Seq] = node
+	}
+	return index
+}
+
+func nameKey(node *model.OccupationNode) string {
+	return fmt.Sprintf("%d|%s", node.Level, node.Name)
+}
+
+func parentOf(node *model.OccupationNode) string {
+	if node.ParentSeq == nil {
+		return ""
+	}
+	return *node.ParentSeq
+}
+
+// sortChanges 按变化类型、编号排序，删除类按旧编号
+func sortChanges(changes []Change) {
+	rank := make(map[Kind]int, len(Kinds))
+	for i, kind := range Kinds {
+		rank[kind] = i
+	}
+	key := func(c Change) string {
+		if c.Seq != "" {
+			return c.Seq
+		}
+		return c.OldSeq
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return rank[changes[i].Kind] < rank[changes[j].Kind]
+		}
+		return key(changes[i]) < key(changes[j])
+	})
+}
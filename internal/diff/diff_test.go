@@ -0,0 +1,108 @@
+package diff_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/diff"
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+func node(seq, name, gbm string) *model.OccupationNode {
+	n := &model.OccupationNode{Seq: seq, Name: name, GBM: gbm, Level: model.LevelOf(seq)}
+	if parent := model.GetParentSeq(seq); parent != "" {
+		n.ParentSeq = &parent
+	}
+	return n
+}
+
+func TestCompare(t *testing.T) {
+	moved := node("2-01-04-03", "体育学研究人员", "")
+	parent := "2-01-02"
+	moved.ParentSeq = &parent
+
+	before := []*model.OccupationNode{
+		node("2-01-02-00", "经济学研究人员", "2010200"),
+		node("2-01-03-00", "法学研究人员", ""),
+		node("2-01-04-01", "教育学研究人员", ""),
+		node("2-01-04-02", "心理学研究人员", ""),
+		node("2-01-04-03", "体育学研究人员", ""),
+		node("2-01-04-04", "社会学研究人员", ""),
+	}
+	after := []*model.OccupationNode{
+		node("2-01-02-00", "经济学研究人员", "2010299"),
+		node("2-01-04-01", "教育研究人员", ""),
+		node("2-01-04-12", "心理学研究人员", ""),
+		moved,
+		node("2-01-04-04", "飞行器驾驶员", ""),
+		node("2-01-05-01", "数据分析人员", ""),
+	}
+
+	changes := diff.Compare(before, after, diff.Options{})
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, string(c.Kind)+" "+c.OldSeq+">"+c.Seq)
+	}
+	want := []string{
+		"added >2-01-04-04",
+		"added >2-01-05-01",
+		"removed 2-01-03-00>",
+		"removed 2-01-04-04>",
+		"renamed 2-01-04-01>2-01-04-01",
+		"moved 2-01-04-03>2-01-04-03",
+		"recoded 2-01-02-00>2-01-02-00",
+		"recoded 2-01-04-02>2-01-04-12",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCompareRecodedAndMoved(t *testing.T) {
+	// 2-02-10 整体改号为 2-02-11，子节点随之改号，不算移动；
+	// 2-02-10-05 改号为 2-02-12-01 并换到另一个小类下，同时记为 recoded 和 moved
+	before := []*model.OccupationNode{
+		node("2-02-10", "信息和通信工程技术人员", "20210"),
+		node("2-02-10-03", "计算机软件工程技术人员", "2021003"),
+		node("2-02-10-05", "信息系统分析工程技术人员", "2021005"),
+		node("2-02-12", "数据工程技术人员", "20212"),
+	}
+	after := []*model.OccupationNode{
+		node("2-02-11", "信息和通信工程技术人员", "20211"),
+		node("2-02-11-03", "计算机软件工程技术人员", "2021103"),
+		node("2-02-12", "数据工程技术人员", "20212"),
+		node("2-02-12-01", "信息系统分析工程技术人员", "2021201"),
+	}
+
+	var got []string
+	for _, c := range diff.Compare(before, after, diff.Options{}) {
+		got = append(got, string(c.Kind)+" "+c.OldSeq+">"+c.Seq+" "+c.OldParentSeq+">"+c.ParentSeq+" "+c.OldGBM+">"+c.GBM)
+	}
+	want := []string{
+		"moved 2-02-10-05>2-02-12-01 2-02-10>2-02-12 2021005>2021201",
+		"recoded 2-02-10>2-02-11 2-02>2-02 20210>20211",
+		"recoded 2-02-10-03>2-02-11-03 2-02-10>2-02-11 2021003>2021103",
+		"recoded 2-02-10-05>2-02-12-01 2-02-10>2-02-12 2021005>2021201",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReportMarkdown(t *testing.T) {
+	report := diff.NewReport("2015", []*model.OccupationNode{node("1", "负责人", "")},
+		"2022", []*model.OccupationNode{node("1", "单位负责人", "")}, diff.Options{})
+
+	if report.Summary[diff.KindRenamed] != 1 {
+		t.Fatalf("got summary %v", report.Summary)
+	}
+
+	var b strings.Builder
+	if err := report.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| 1 | 负责人 | 单位负责人 | 0.60 |") {
+		t.Errorf("markdown missing rename row:\n%s", b.String())
+	}
+}
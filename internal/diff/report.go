@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+// Report 两个版本(或两次导出)之间的差异报告
+type Report struct {
+	Old         string       `json:"old"`
+	New         string       `json:"new"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Threshold   float64      `json:"threshold"`
+	Summary     map[Kind]int `json:"summary"`
+	Changes     []Change     `json:"changes"`
+}
+
+// NewReport 比较 before 与 after 并生成报告，oldLabel/newLabel 为版本号或文件名
+func NewReport(oldLabel string, before []*model.OccupationNode, newLabel string, after []*model.OccupationNode, opts Options) *Report {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+
+	report := &Report{
+		Old:         oldLabel,
+		New:         newLabel,
+		GeneratedAt: time.Now(),
+		Threshold:   opts.Threshold,
+		Summary:     make(map[Kind]int, len(Kinds)),
+		Changes:     Compare(before, after, opts),
+	}
+	for _, kind := range Kinds {
+		report.Summary[kind] = 0
+	}
+	for _, c := range report.Changes {
+		report.Summary[c.Kind]++
+	}
+
+	return report
+}
+
+func (r *Report) WriteJSON(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal diff: %w", err)
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func (r *Report) WriteMarkdownFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create markdown file: %w", err)
+	}
+	defer f.Close()
+
+	return r.WriteMarkdown(f)
+}
+
+var kindTitles = map[Kind]string{
+	KindAdded:   "新增",
+	KindRemoved: "删除",
+	KindRenamed: "更名",
+	KindMoved:   "调整上级",
+	KindRecoded: "编码变化",
+}
+
+// WriteMarkdown 输出供人工阅读的 Markdown 报告
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# 职业分类差异：%s → %s\n\n", r.Old, r.New)
+	fmt.Fprintf(&b, "生成时间：%s，名称相似度阈值：%.2f\n\n", r.GeneratedAt.Format("2006-01-02 15:04:05"), r.Threshold)
+
+	b.WriteString("| 变化 | 数量 |\n|------|------|\n")
+	for _, kind := range Kinds {
+		fmt.Fprintf(&b, "| %s | %d |\n", kindTitles[kind], r.Summary[kind])
+	}
+
+	for _, kind := range Kinds {
+		if r.Summary[kind] == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", kindTitles[kind], r.Summary[kind])
+		switch kind {
+		case KindAdded:
+			b.WriteString("| 编号 | 名称 | 层级 | 上级 |\n|------|------|------|------|\n")
+		case KindRemoved:
+			b.WriteString("| 原编号 | 名称 | 层级 | 原上级 |\n|------|------|------|------|\n")
+		case KindRenamed:
+			b.WriteString("| 编号 | 原名称 | 新名称 | 相似度 |\n|------|------|------|------|\n")
+		case KindMoved:
+			b.WriteString("| 编号 | 名称 | 原上级 | 新上级 |\n|------|------|------|------|\n")
+		case KindRecoded:
+			b.WriteString("| 原编号 | 新编号 | 名称 | 原GBM | 新GBM |\n|------|------|------|------|------|\n")
+		}
+
+		for _, c := range r.Changes {
+			if c.Kind != kind {
+				continue
+			}
+			switch kind {
+			case KindAdded:
+				fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", c.Seq, c.Name, c.Level, c.ParentSeq)
+			case KindRemoved:
+				fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", c.OldSeq, c.OldName, c.Level, c.OldParentSeq)
+			case KindRenamed:
+				fmt.Fprintf(&b, "| %s | %s | %s | %.2f |\n", seqLabel(c), c.OldName, c.Name, c.Similarity)
+			case KindMoved:
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", c.Seq, c.Name, c.OldParentSeq, c.ParentSeq)
+			case KindRecoded:
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", c.OldSeq, c.Seq, c.Name, c.OldGBM, c.GBM)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// seqLabel 编号未变时只显示一个编号
+func seqLabel(c Change) string {
+	if c.OldSeq == c.Seq {
+		return c.Seq
+	}
+	return c.OldSeq + " → " + c.Seq
+}
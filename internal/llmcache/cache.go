@@ -0,0 +1,215 @@
+package llmcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key 决定缓存命中的全部输入，任一字段变化都视为不同的请求
+type Key struct {
+	Model         string  `json:"model"`
+	PromptVersion string  `json:"prompt_version"`
+	Temperature   float64 `json:"temperature"`
+	Input         string  `json:"input"`           // 清洗后的名称文本
+	Codes         string  `json:"codes,omitempty"` // 要求与名称一一对应的代码，空格分隔
+}
+
+// Hash 返回缓存文件名使用的 SHA-256
+func (k Key) Hash() string {
+	h := sha256.New()
+	for _, part := range []string{k.Model, k.PromptVersion, strconv.FormatFloat(k.Temperature, 'g', -1, 64), k.Input, k.Codes} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Entry 一条缓存的大模型结果
+type Entry struct {
+	Hash string `json:"hash"`
+	Key
+	Names     []string  `json:"names"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Filter 选择缓存条目，零值匹配全部
+type Filter struct {
+	Model         string
+	PromptVersion string
+	// 提示词版本不等于该值，用于清理旧版本提示词的结果
+	NotPromptVersion string
+	Contains         string // 输入包含该文本
+	Before           time.Time
+}
+
+func (f Filter) Match(e *Entry) bool {
+	switch {
+	case f.Model != "" && e.Model != f.Model:
+		return false
+	case f.PromptVersion != "" && e.PromptVersion != f.PromptVersion:
+		return false
+	case f.NotPromptVersion != "" && e.PromptVersion == f.NotPromptVersion:
+		return false
+	case f.Contains != "" && !strings.Contains(e.Input, f.Contains):
+		return false
+	case !f.Before.IsZero() && !e.CreatedAt.Before(f.Before):
+		return false
+	}
+	return true
+}
+
+// Cache 大模型结果的磁盘缓存，每条结果一个 JSON 文件，可在多个 goroutine 中同时使用
+type Cache struct {
+	dir string
+}
+
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.dir, hash+".json")
+}
+
+// Get 查找缓存，未命中或文件损坏时返回 false
+func (c *Cache) Get(key Key) (*Entry, bool) {
+	entry, err := c.read(c.path(key.Hash()))
+	if err != nil || entry.Key != key {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Put 写入缓存，先写临时文件再改名，避免并发读到半个文件
+func (c *Cache) Put(key Key, names []string) error {
+	entry := Entry{
+		Hash:      key.Hash(),
+		Key:       key,
+		Names:     names,
+		CreatedAt: time.Now(),
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, entry.Hash+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(entry.Hash)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save cache entry: %w", err)
+	}
+	return nil
+}
+
+// List 返回匹配的条目，按创建时间排序
+func (c *Cache) List(filter Filter) ([]*Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, path := range paths {
+		entry, err := c.read(path)
+		if err != nil {
+			fmt.Printf("Warning: skipping unreadable cache entry %s: %v\n", path, err)
+			continue
+		}
+		if filter.Match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		}
+		return entries[i].Hash < entries[j].Hash
+	})
+	return entries, nil
+}
+
+// Remove 删除一条缓存
+func (c *Cache) Remove(hash string) error {
+	if err := os.Remove(c.path(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove cache entry %s: %w", hash, err)
+	}
+	return nil
+}
+
+// Invalidate 删除匹配的条目，返回删除数量
+func (c *Cache) Invalidate(filter Filter) (int, error) {
+	entries, err := c.List(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, entry := range entries {
+		if err := c.Remove(entry.Hash); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// WriteJSONL 每行写出一个条目
+func WriteJSONL(w io.Writer, entries []*Entry) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to export cache entry: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Cache) read(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+
+	// 截断或手工修改过的文件，哈希可能为空或与文件名、键不符
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	if entry.Hash != name || entry.Hash != entry.Key.Hash() {
+		return nil, fmt.Errorf("hash %q does not match file name or key", entry.Hash)
+	}
+	return &entry, nil
+}
@@ -0,0 +1,77 @@
+package llmcache_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/llmcache"
+)
+
+func TestCache(t *testing.T) {
+	cache, err := llmcache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := llmcache.Key{Model: "qwen-plus", PromptVersion: "1", Temperature: 0.1, Input: "计算机软件工程\n技术人员"}
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("unexpected hit on an empty cache")
+	}
+	if err := cache.Put(key, []string{"计算机软件工程技术人员"}); err != nil {
+		t.Fatal(err)
+	}
+	if entry, ok := cache.Get(key); !ok || len(entry.Names) != 1 || entry.Hash != key.Hash() {
+		t.Errorf("got %+v, %v", entry, ok)
+	}
+
+	// 模型、提示词版本、温度任一不同都不命中
+	for _, other := range []llmcache.Key{
+		{Model: "qwen-max", PromptVersion: "1", Temperature: 0.1, Input: key.Input},
+		{Model: "qwen-plus", PromptVersion: "2", Temperature: 0.1, Input: key.Input},
+		{Model: "qwen-plus", PromptVersion: "1", Temperature: 0.2, Input: key.Input},
+	} {
+		if _, ok := cache.Get(other); ok {
+			t.Errorf("unexpected hit for %+v", other)
+		}
+		if err := cache.Put(other, []string{"x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	entries, err := cache.List(llmcache.Filter{Model: "qwen-plus"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := llmcache.WriteJSONL(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 3 {
+		t.Errorf("exported %d lines, want 3", lines)
+	}
+
+	removed, err := cache.Invalidate(llmcache.Filter{NotPromptVersion: "1"})
+	if err != nil || removed != 1 {
+		t.Errorf("removed %d stale entries (%v), want 1", removed, err)
+	}
+	if all, _ := cache.List(llmcache.Filter{}); len(all) != 3 {
+		t.Errorf("got %d entries after invalidation, want 3", len(all))
+	}
+
+	// 哈希为空、过短或与文件名不符的条目被跳过
+	for name, content := range map[string]string{
+		"empty.json":             `{"model": "qwen-plus", "names": []}`,
+		"abc.json":               `{"hash": "abc", "model": "qwen-plus"}`,
+		key.Hash()[:8] + ".json": `{"hash": "` + key.Hash() + `", "model": "qwen-plus"}`,
+	} {
+		if err := os.WriteFile(filepath.Join(cache.Dir(), name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if all, err := cache.List(llmcache.Filter{}); err != nil || len(all) != 3 {
+		t.Errorf("got %d entries (%v) with damaged files, want 3", len(all), err)
+	}
+}
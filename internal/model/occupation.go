@@ -1,19 +1,75 @@
 package model
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
 
 type OccupationNode struct {
 	ID        int64     `json:"id" db:"id"`
+	Edition   string    `json:"edition" db:"edition"` // 大典版本，如 2022
 	Seq       string    `json:"seq" db:"seq"`
 	GBM       string    `json:"gbm" db:"gbm"`
 	Name      string    `json:"name" db:"name"`
 	Level     int       `json:"level" db:"level"`
 	ParentSeq *string   `json:"parent_seq" db:"parent_seq"`
+	Green     bool      `json:"green" db:"is_green"`     // 绿色职业(L)
+	Digital   bool      `json:"digital" db:"is_digital"` // 数字职业(S)
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// 细类的职业描述：定义及按顺序排列的主要工作任务
+	Definition string   `json:"definition,omitempty" db:"definition"`
+	Tasks      []string `json:"tasks,omitempty" db:"-"`
+
+	Source   *Provenance `json:"source,omitempty" db:"-"`
+	Mappings []Mapping   `json:"mappings,omitempty" db:"-"`
+}
+
+// Mapping 对照表中的一条映射，System 为 "isco08" 或其他大典版本号
+type Mapping struct {
+	System   string `json:"system" db:"target_system"`
+	Code     string `json:"code" db:"target_code"`
+	Relation string `json:"relation" db:"relation"` // exact, broader, narrower, partial
+}
+
+// Provenance 节点在源工作簿中的位置，便于回溯到原始单元格
+type Provenance struct {
+	File    string `json:"file,omitempty" db:"file_name"`
+	Sheet   string `json:"sheet" db:"sheet_name"`
+	Row     int    `json:"row" db:"row_num"`
+	Column  string `json:"column" db:"column_name"`
+	RawText string `json:"raw_text" db:"raw_text"`
+}
+
+// Cell 返回单元格坐标，如 "F17"
+func (p *Provenance) Cell() string {
+	return fmt.Sprintf("%s%d", p.Column, p.Row)
+}
+
+func (p *Provenance) String() string {
+	if p == nil {
+		return "unknown"
+	}
+	if p.File != "" {
+		return fmt.Sprintf("%s[%s]!%s", p.File, p.Sheet, p.Cell())
+	}
+	return fmt.Sprintf("[%s]!%s", p.Sheet, p.Cell())
+}
+
+// MarkerSuffix 返回职业标识的原始写法，如 " L/S"，无标识时为空
+func (n *OccupationNode) MarkerSuffix() string {
+	switch {
+	case n.Green && n.Digital:
+		return " L/S"
+	case n.Green:
+		return " L"
+	case n.Digital:
+		return " S"
+	default:
+		return ""
+	}
 }
 
 type ParseResult struct {
@@ -21,6 +77,61 @@ type ParseResult struct {
 	Middles   []*OccupationNode
 	Minors    []*OccupationNode
 	SubMinors []*OccupationNode
+	SubTypes  []*OccupationNode // 工种，层级 5
+
+	// 职业描述部分解析出的细类，BuildHierarchy 时合并到同编号的细类
+	Descriptions []*OccupationNode
+
+	Conflicts []*SeqConflict
+}
+
+// SeqConflict 合并多个工作表或文件时出现的重复编号，保留先出现的节点
+type SeqConflict struct {
+	Seq      string          `json:"seq"`
+	Kept     *OccupationNode `json:"kept"`
+	Dropped  *OccupationNode `json:"dropped"`
+	SameName bool            `json:"same_name"`
+	SameGBM  bool            `json:"same_gbm"`
+}
+
+// Merge 将 other 的节点按层级追加到当前结果，编号重复的节点记为冲突
+func (pr *ParseResult) Merge(other *ParseResult) {
+	if other == nil {
+		return
+	}
+
+	seen := make(map[string]*OccupationNode)
+	for _, nodes := range [][]*OccupationNode{pr.Majors, pr.Middles, pr.Minors, pr.SubMinors, pr.SubTypes} {
+		for _, node := range nodes {
+			seen[node.Seq] = node
+		}
+	}
+
+	merge := func(dst []*OccupationNode, src []*OccupationNode) []*OccupationNode {
+		for _, node := range src {
+			if kept, exists := seen[node.Seq]; exists {
+				pr.Conflicts = append(pr.Conflicts, &SeqConflict{
+					Seq:      node.Seq,
+					Kept:     kept,
+					Dropped:  node,
+					SameName: kept.Name == node.Name,
+					SameGBM:  kept.GBM == node.GBM,
+				})
+				continue
+			}
+			seen[node.Seq] = node
+			dst = append(dst, node)
+		}
+		return dst
+	}
+
+	pr.Majors = merge(pr.Majors, other.Majors)
+	pr.Middles = merge(pr.Middles, other.Middles)
+	pr.Minors = merge(pr.Minors, other.Minors)
+	pr.SubMinors = merge(pr.SubMinors, other.SubMinors)
+	pr.SubTypes = merge(pr.SubTypes, other.SubTypes)
+	pr.Descriptions = append(pr.Descriptions, other.Descriptions...)
+	pr.Conflicts = append(pr.Conflicts, other.Conflicts...)
 }
 
 // 建立父子关系
@@ -48,15 +159,104 @@ func (pr *ParseResult) BuildHierarchy() []*OccupationNode {
 	}
 
 	// 添加细类（父级为小类）
-	for _, subMinor := range pr.SubMinors {
+	for _, subMinor := range pr.mergeDescriptions() {
 		parentSeq := GetParentSeq(subMinor.Seq) // 小类是X-XX-XX格式
 		subMinor.ParentSeq = &parentSeq
 		allNodes = append(allNodes, subMinor)
 	}
 
+	// 添加工种（父级为细类）
+	for _, subType := range pr.SubTypes {
+		parentSeq := GetParentSeq(subType.Seq)
+		subType.ParentSeq = &parentSeq
+		allNodes = append(allNodes, subType)
+	}
+
 	return allNodes
 }
 
+// mergeDescriptions 将职业描述中的定义和任务合并到同编号的细类，
+// 表格中没有的细类以描述中的编号和名称新增
+func (pr *ParseResult) mergeDescriptions() []*OccupationNode {
+	index := make(map[string]*OccupationNode, len(pr.SubMinors))
+	for _, node := range pr.SubMinors {
+		index[node.Seq] = node
+	}
+
+	for _, desc := range pr.Descriptions {
+		node, ok := index[desc.Seq]
+		if !ok {
+			pr.SubMinors = append(pr.SubMinors, desc)
+			index[desc.Seq] = desc
+			continue
+		}
+		if desc.Definition != "" {
+			node.Definition = desc.Definition
+		}
+		if len(desc.Tasks) > 0 {
+			node.Tasks = desc.Tasks
+		}
+	}
+	pr.Descriptions = nil
+
+	return pr.SubMinors
+}
+
+// LevelOf 根据编号段数返回层级，如 "1-01-00" 为 3
+func LevelOf(seq string) int {
+	return len(strings.Split(seq, "-"))
+}
+
+// DeriveGBM 按编号推导 GBM 编码：大类1位、中类2位、小类2位，不足5位补0，细类在小类编码后追加2位，
+// 如 2 → 20000、2-02-10 → 20210、2-02-10-03 → 2021003。编号格式不合法时返回空串
+func DeriveGBM(seq string) string {
+	parts := strings.Split(seq, "-")
+	if len(parts) > 4 {
+		return ""
+	}
+	for i, part := range parts {
+		width := 2
+		if i == 0 {
+			width = 1
+		}
+		if len(part) != width || strings.Trim(part, "0123456789") != "" {
+			return ""
+		}
+	}
+
+	gbm := strings.Join(parts, "")
+	if len(parts) < 4 {
+		gbm += strings.Repeat("0", 5-len(gbm))
+	}
+	return gbm
+}
+
+// SetEdition 为所有节点设置大典版本
+func (pr *ParseResult) SetEdition(edition string) {
+	for _, nodes := range [][]*OccupationNode{pr.Majors, pr.Middles, pr.Minors, pr.SubMinors, pr.SubTypes, pr.Descriptions} {
+		for _, node := range nodes {
+			node.Edition = edition
+		}
+	}
+}
+
+// DeriveDetailGBM 为没有 GBM 的细类按编号推导 GBM，返回推导的数量
+func (pr *ParseResult) DeriveDetailGBM() int {
+	derived := 0
+	for _, nodes := range [][]*OccupationNode{pr.SubMinors, pr.Descriptions} {
+		for _, node := range nodes {
+			if node.GBM != "" {
+				continue
+			}
+			if gbm := DeriveGBM(node.Seq); gbm != "" {
+				node.GBM = gbm
+				derived++
+			}
+		}
+	}
+	return derived
+}
+
 // 根据子节点编号获取父节点编号
 func GetParentSeq(childSeq string) string {
 	parts := strings.Split(childSeq, "-")
@@ -75,6 +275,9 @@ func GetParentSeq(childSeq string) string {
 	case 4:
 		// 细类（如1-01-01-01），父级是小类（如1-01-01）
 		return strings.Join(parts[:3], "-")
+	case 5:
+		// 工种（如1-01-01-01-01），父级是细类
+		return strings.Join(parts[:4], "-")
 	default:
 		return ""
 	}
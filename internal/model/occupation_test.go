@@ -12,3 +12,70 @@ func TestGetParentSeq(t *testing.T) {
 	fmt.Println(model.GetParentSeq("1-01-00"))    // "1-01"
 	fmt.Println(model.GetParentSeq("1-01-00-01")) // "1-01-00"
 }
+
+func TestDeriveGBM(t *testing.T) {
+	tests := map[string]string{
+		"1":          "10000",
+		"1-01":       "10100",
+		"2-10":       "21000",
+		"2-02-10":    "20210",
+		"2-02-10-03": "2021003",
+		"2-O2":       "",
+		"2-2-10":     "",
+	}
+	for seq, want := range tests {
+		if got := model.DeriveGBM(seq); got != want {
+			t.Errorf("DeriveGBM(%q) = %q, want %q", seq, got, want)
+		}
+	}
+}
+
+func TestParseResultMergeConflicts(t *testing.T) {
+	node := func(seq, gbm, name, sheet string) *model.OccupationNode {
+		return &model.OccupationNode{Seq: seq, GBM: gbm, Name: name, Source: &model.Provenance{Sheet: sheet}}
+	}
+
+	result := &model.ParseResult{}
+	result.Merge(&model.ParseResult{
+		Majors:    []*model.OccupationNode{node("2", "20000", "专业技术人员", "卷一")},
+		Middles:   []*model.OccupationNode{node("2-02", "20200", "工程技术人员", "卷一")},
+		SubMinors: []*model.OccupationNode{node("2-02-10-03", "", "计算机软件工程技术人员", "卷一")},
+	})
+	result.Merge(&model.ParseResult{
+		Majors:    []*model.OccupationNode{node("2", "20000", "专业技术人员", "卷二")},
+		Middles:   []*model.OccupationNode{node("2-02", "20210", "工程技术人员", "卷二")},
+		SubMinors: []*model.OccupationNode{node("2-02-10-03", "", "计算机网络工程技术人员", "卷二"), node("2-02-10-04", "", "计算机网络工程技术人员", "卷二")},
+		Conflicts: []*model.SeqConflict{{Seq: "2-02-10-05"}},
+	})
+	result.Merge(nil)
+
+	if len(result.Majors) != 1 || len(result.Middles) != 1 || len(result.SubMinors) != 2 {
+		t.Fatalf("got %d majors, %d middles, %d details", len(result.Majors), len(result.Middles), len(result.SubMinors))
+	}
+	if result.Middles[0].GBM != "20200" || result.SubMinors[0].Name != "计算机软件工程技术人员" {
+		t.Errorf("kept %+v and %+v, want the first volume's nodes", result.Middles[0], result.SubMinors[0])
+	}
+
+	tests := []struct {
+		seq      string
+		sameName bool
+		sameGBM  bool
+	}{
+		{"2", true, true},
+		{"2-02", true, false},
+		{"2-02-10-03", false, true},
+		{"2-02-10-05", false, false},
+	}
+	if len(result.Conflicts) != len(tests) {
+		t.Fatalf("got %d conflicts, want %d", len(result.Conflicts), len(tests))
+	}
+	for i, tt := range tests {
+		c := result.Conflicts[i]
+		if c.Seq != tt.seq || c.SameName != tt.sameName || c.SameGBM != tt.sameGBM {
+			t.Errorf("conflict %d = %s same_name=%v same_gbm=%v, want %s %v %v", i, c.Seq, c.SameName, c.SameGBM, tt.seq, tt.sameName, tt.sameGBM)
+		}
+		if c.Kept != nil && (c.Kept.Source.Sheet != "卷一" || c.Dropped.Source.Sheet != "卷二") {
+			t.Errorf("conflict %s kept %s and dropped %s", c.Seq, c.Kept.Source.Sheet, c.Dropped.Source.Sheet)
+		}
+	}
+}
@@ -5,10 +5,18 @@ import (
 )
 
 type TreeNode struct {
-	Seq      string      `json:"seq"`
-	GBM      string      `json:"gbm,omitempty"`
-	Name     string      `json:"name"`
-	Level    int         `json:"level"`
+	Seq     string `json:"seq"`
+	GBM     string `json:"gbm,omitempty"`
+	Name    string `json:"name"`
+	Level   int    `json:"level"`
+	Green   bool   `json:"green,omitempty"`
+	Digital bool   `json:"digital,omitempty"`
+
+	Definition string   `json:"definition,omitempty"`
+	Tasks      []string `json:"tasks,omitempty"`
+
+	Source   *Provenance `json:"source,omitempty"`
+	Mappings []Mapping   `json:"mappings,omitempty"`
 	Children []*TreeNode `json:"children,omitempty"`
 }
 
@@ -18,11 +26,17 @@ func BuildOccupationTree(occupations []*OccupationNode) []*TreeNode {
 
 	for _, occ := range occupations {
 		treeNode := &TreeNode{
-			Seq:      occ.Seq,
-			GBM:      occ.GBM,
-			Name:     occ.Name,
-			Level:    occ.Level,
-			Children: []*TreeNode{},
+			Seq:        occ.Seq,
+			GBM:        occ.GBM,
+			Name:       occ.Name,
+			Level:      occ.Level,
+			Green:      occ.Green,
+			Digital:    occ.Digital,
+			Definition: occ.Definition,
+			Tasks:      occ.Tasks,
+			Source:     occ.Source,
+			Mappings:   occ.Mappings,
+			Children:   []*TreeNode{},
 		}
 		nodeMap[occ.Seq] = treeNode
 	}
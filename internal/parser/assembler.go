@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+type recordKind int
+
+const (
+	recordComplete recordKind = iota // 代码和名称都有
+	recordCodes                      // 只有代码，名称在后续行
+	recordNames                      // 只有名称，代码在后续行或为上一条记录的续行
+	recordMerged                     // 代码与名称在同一单元格
+)
+
+// detailRecord 一条细类记录，可能由跨行、跨页(续表)的多行拼接而成
+type detailRecord struct {
+	row   sheetRow // 起始行，作为节点来源和不匹配日志的行号
+	rows  []int
+	kind  recordKind
+	codes string
+	names string
+}
+
+// ContinuationRecord 一次跨行拼接
+type ContinuationRecord struct {
+	File   string `json:"file"`
+	Sheet  string `json:"sheet"`
+	Row    int    `json:"row"`  // 被拼接的行
+	Into   int    `json:"into"` // 拼接到的记录起始行
+	Reason string `json:"reason"`
+}
+
+// assembleDetails 将细类列按行切分为记录，并把不完整的记录与相邻行拼接：
+// 只有代码的行与后续的代码、名称行拼接，只有名称的行与后续的代码行拼接，
+// 其余只有名称的行视为上一条记录被截断的名称。表头和续表行已在 filterRows 中删除，
+// 因此跨页的记录在这里同样相邻；空行不打断拼接，出现中类、小类等其他内容时打断
+func (p *sheetParser) assembleDetails(rows []sheetRow) []*detailRecord {
+	var pending []*detailRecord
+	var breaks []bool
+	for _, row := range rows {
+		if p.majorRows[row.Num] {
+			pending = append(pending, nil)
+			breaks = append(breaks, true)
+			continue
+		}
+		rec := p.detailRow(row)
+		pending = append(pending, rec)
+		breaks = append(breaks, rec == nil && hasContent(row))
+	}
+
+	var records []*detailRecord
+	var last *detailRecord
+	for i, rec := range pending {
+		if rec == nil {
+			if breaks[i] {
+				last = nil
+			}
+			continue
+		}
+
+		if last != nil {
+			if reason := p.absorb(last, rec, nextRecord(pending, breaks, i)); reason != "" {
+				last.rows = append(last.rows, rec.row.Num)
+				fmt.Printf("Line %-3d continued into line %d (%s)\n", rec.row.Num, last.row.Num, reason)
+				p.report.Continuations = append(p.report.Continuations, ContinuationRecord{
+					File:   p.file,
+					Sheet:  p.sheet,
+					Row:    rec.row.Num,
+					Into:   last.row.Num,
+					Reason: reason,
+				})
+				continue
+			}
+		}
+
+		records = append(records, rec)
+		last = rec
+	}
+
+	return records
+}
+
+// detailRow 按细类代码列和名称列的内容判断一行的记录类型，两列都为空时返回 nil
+func (p *sheetParser) detailRow(row sheetRow) *detailRecord {
+	separated := p.layout.DetailName != p.layout.DetailCode
+	codes := strings.TrimSpace(cell(row, p.layout.DetailCode))
+	names := ""
+	if separated {
+		names = strings.TrimSpace(cell(row, p.layout.DetailName))
+	}
+
+	rec := &detailRecord{row: row, rows: []int{row.Num}, codes: codes, names: names}
+	switch {
+	case codes == "" && names == "":
+		return nil
+	case !separated || (names == "" && ChineseRegex.MatchString(codes)):
+		rec.kind = recordMerged
+	case names == "":
+		rec.kind = recordCodes
+	case codes == "":
+		rec.kind = recordNames
+	default:
+		rec.kind = recordComplete
+	}
+	return rec
+}
+
+// absorb 尝试把 rec 拼接到 last，返回拼接原因，不能拼接时返回空串
+func (p *sheetParser) absorb(last, rec, next *detailRecord) string {
+	switch {
+	case last.kind == recordCodes && rec.kind == recordCodes:
+		last.codes += "\n" + rec.codes
+		return "codes continued"
+	case last.kind == recordCodes && rec.kind == recordNames:
+		last.names, last.kind = rec.names, recordComplete
+		return "names for codes"
+	case last.kind == recordNames && rec.kind == recordCodes:
+		last.codes, last.kind = rec.codes, recordComplete
+		return "codes for names"
+	case last.kind == recordComplete && rec.kind == recordNames:
+		// 下一行只有代码且数量与名称一致时，这些名称属于下一条记录
+		if next != nil && next.kind == recordCodes && len(strings.Fields(next.codes)) == p.countNames(rec) {
+			return ""
+		}
+		// 名称少于代码时补足缺少的名称，否则接在最后一个名称之后
+		if len(strings.Fields(last.codes)) > p.countNames(last) {
+			last.names += "\n" + rec.names
+			return "names continued"
+		}
+		last.names += rec.names
+		return "name fragment"
+	case last.kind == recordMerged && rec.kind == recordMerged:
+		if endsWithCode(last.codes) || !startsWithCode(rec.codes) {
+			last.codes += rec.codes
+			return "merged continuation"
+		}
+	}
+	return ""
+}
+
+// countNames 按行估算名称数量，用于判断记录是否完整
+func (p *sheetParser) countNames(rec *detailRecord) int {
+	return len(splitNameLines(stripMarkers(rec.names), p.normalizer))
+}
+
+// nextRecord 返回 i 之后的下一条记录，遇到打断拼接的行时返回 nil
+func nextRecord(pending []*detailRecord, breaks []bool, i int) *detailRecord {
+	for j := i + 1; j < len(pending); j++ {
+		if pending[j] != nil {
+			return pending[j]
+		}
+		if breaks[j] {
+			return nil
+		}
+	}
+	return nil
+}
+
+func hasContent(row sheetRow) bool {
+	for _, text := range row.Cells {
+		if strings.TrimSpace(text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWithCode 合并单元格的最后一个代码之后没有名称
+func endsWithCode(text string) bool {
+	cleaned := strings.Join(strings.Fields(text), "")
+	locs := DetailCodeRegex.FindAllStringIndex(cleaned, -1)
+	return len(locs) > 0 && locs[len(locs)-1][1] == len(cleaned)
+}
+
+func startsWithCode(text string) bool {
+	cleaned := strings.Join(strings.Fields(text), "")
+	loc := DetailCodeRegex.FindStringIndex(cleaned)
+	return loc != nil && loc[0] == 0
+}
+
+// continuedRows 拼接而成的记录返回涉及的全部行，单行记录返回 nil
+func continuedRows(rec *detailRecord) []int {
+	if len(rec.rows) < 2 {
+		return nil
+	}
+	return rec.rows
+}
@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// CodeSubstitution 编码预处理中对单元格所做的一类替换
+type CodeSubstitution struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Count  int    `json:"count"`
+	Reason string `json:"reason"` // "nfkc" 或 "ocr"
+}
+
+func (s CodeSubstitution) String() string {
+	return fmt.Sprintf("%q -> %q x%d (%s)", s.From, s.To, s.Count, s.Reason)
+}
+
+// ocrConfusions 编码上下文中常见的 OCR 误识别字符
+var ocrConfusions = map[rune]rune{
+	'O': '0', 'o': '0',
+	'I': '1', 'l': '1', '|': '1',
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '―': '-', '−': '-', '一': '-',
+}
+
+// normalizeCodes 在编码上下文(代码、GBM 编码及大类编号)中做 NFKC 规范化并修正 OCR 误识别字符，
+// 名称等其余文本保持原样，返回结果及全部替换
+func normalizeCodes(text string) (string, []CodeSubstitution) {
+	counts := make(map[CodeSubstitution]int)
+
+	fix := func(context string, keepSpaces bool) string {
+		var out strings.Builder
+		for _, r := range context {
+			if !keepSpaces && (r == ' ' || r == '\t') {
+				continue
+			}
+			if to, ok := ocrConfusions[r]; ok {
+				counts[CodeSubstitution{From: string(r), To: string(to), Reason: "ocr"}]++
+				r = to
+			}
+			out.WriteRune(r)
+		}
+		return out.String()
+	}
+
+	text = replaceFolded(text, CodeContextRegex, counts, func(m string) (string, bool) {
+		if !strings.ContainsAny(m, "0123456789") {
+			return m, false
+		}
+		return fix(m, false), true
+	})
+	text = replaceFolded(text, GBMContextRegex, counts, func(m string) (string, bool) {
+		loc := GBMContextRegex.FindStringSubmatchIndex(m)
+		code := m[loc[4]:loc[5]]
+		if !strings.ContainsAny(code, "0123456789") {
+			return m, false
+		}
+		return m[:loc[4]] + fix(code, true) + m[loc[5]:], true
+	})
+	text = replaceFolded(text, MajorCodeContextRegex, counts, func(m string) (string, bool) {
+		return m, true
+	})
+
+	subs := make([]CodeSubstitution, 0, len(counts))
+	for s, n := range counts {
+		s.Count = n
+		subs = append(subs, s)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Reason != subs[j].Reason {
+			return subs[i].Reason < subs[j].Reason
+		}
+		return subs[i].From < subs[j].From
+	})
+
+	return text, subs
+}
+
+// foldedRune 单个字符及其 NFKC 结果在规范化文本中的字节范围
+type foldedRune struct {
+	orig, folded string
+	start, end   int
+}
+
+// replaceFolded 在逐字符 NFKC 后的文本中查找 re 的匹配，用 fix 的结果替换匹配部分，
+// 匹配之外保留原文；fix 返回 false 时保留该处原文。只统计被替换部分的 NFKC 变化
+func replaceFolded(text string, re *regexp.Regexp, counts map[CodeSubstitution]int, fix func(string) (string, bool)) string {
+	var runes []foldedRune
+	var b strings.Builder
+	for _, r := range text {
+		folded := norm.NFKC.String(string(r))
+		runes = append(runes, foldedRune{orig: string(r), folded: folded, start: b.Len(), end: b.Len() + len(folded)})
+		b.WriteString(folded)
+	}
+	folded := b.String()
+
+	var out strings.Builder
+	i := 0
+	for _, loc := range re.FindAllStringIndex(folded, -1) {
+		for i < len(runes) && runes[i].end <= loc[0] {
+			out.WriteString(runes[i].orig)
+			i++
+		}
+		// 匹配边界落在某个字符的 NFKC 结果中间时扩展到整个字符
+		first := i
+		for i < len(runes) && runes[i].start < loc[1] {
+			i++
+		}
+		if first == i {
+			continue
+		}
+		matched := runes[first:i]
+
+		replaced, ok := fix(folded[matched[0].start:matched[len(matched)-1].end])
+		if !ok {
+			for _, r := range matched {
+				out.WriteString(r.orig)
+			}
+			continue
+		}
+		for _, r := range matched {
+			if r.orig != r.folded {
+				counts[CodeSubstitution{From: r.orig, To: r.folded, Reason: "nfkc"}]++
+			}
+		}
+		out.WriteString(replaced)
+	}
+	for ; i < len(runes); i++ {
+		out.WriteString(runes[i].orig)
+	}
+
+	return out.String()
+}
@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+type descriptionSection int
+
+const (
+	sectionNone descriptionSection = iota
+	sectionName
+	sectionDefinition
+	sectionTasks
+	sectionSubTypes
+)
+
+// descriptionLine 职业描述中的一行，Text 为规范化编码后的文本，Raw 为原文
+type descriptionLine struct {
+	row  sheetRow
+	col  int
+	Text string
+	Raw  string
+}
+
+// 工种名称之间以顿号、逗号、分号或连续空白分隔，单个空白视为 OCR 插入的噪声
+var subTypeSeparatorRegex = regexp.MustCompile(`[、，,；;]|\s{2,}|\t`)
+
+// descriptionLines 将各单元格按换行拆分为文本行，保持行、列顺序
+func descriptionLines(rows []sheetRow) []descriptionLine {
+	var lines []descriptionLine
+	for _, row := range rows {
+		for col := range row.Cells {
+			texts := strings.Split(cell(row, col), "\n")
+			raws := strings.Split(rawCell(row, col), "\n")
+			for i, text := range texts {
+				text = strings.TrimSpace(text)
+				if text == "" {
+					continue
+				}
+				raw := text
+				if i < len(raws) {
+					raw = strings.TrimSpace(raws[i])
+				}
+				lines = append(lines, descriptionLine{row: row, col: col, Text: text, Raw: raw})
+			}
+		}
+	}
+	return lines
+}
+
+// descriptionParser 逐行解析职业描述：细类标题、定义、主要工作任务及工种
+type descriptionParser struct {
+	*sheetParser
+
+	details  []*model.OccupationNode
+	subTypes []*model.OccupationNode
+
+	current    *model.OccupationNode
+	section    descriptionSection
+	definition []string
+	tasks      []string
+	// 当前细类的工种，未标编号的 Seq 为空，flush 时在已标编号之后依次编号
+	pendingSubTypes []*model.OccupationNode
+}
+
+// parseDescriptions 解析职业描述工作表，返回带定义和任务的细类以及工种
+func (p *sheetParser) parseDescriptions(rows []sheetRow) ([]*model.OccupationNode, []*model.OccupationNode) {
+	dp := &descriptionParser{sheetParser: p}
+	for _, line := range descriptionLines(rows) {
+		dp.parseLine(line)
+	}
+	dp.flush()
+
+	return dp.details, dp.subTypes
+}
+
+func (dp *descriptionParser) parseLine(line descriptionLine) {
+	if dp.current != nil && dp.section == sectionSubTypes {
+		if m := SubTypeCodeRegex.FindStringSubmatch(line.Text); m != nil {
+			dp.addSubType(line, m[1], m[2])
+			return
+		}
+	}
+
+	if m := DescriptionHeaderRegex.FindStringSubmatch(line.Text); m != nil && !SubTypeCodeRegex.MatchString(line.Text) {
+		dp.flush()
+		dp.start(line, m[1], m[2])
+		return
+	}
+
+	if DescriptionHeadingRegex.MatchString(line.Text) {
+		dp.flush()
+		return
+	}
+
+	if dp.current == nil {
+		return
+	}
+
+	if m := DefinitionLabelRegex.FindStringSubmatch(line.Raw); m != nil {
+		dp.section = sectionDefinition
+		dp.appendText(m[1])
+		return
+	}
+	if m := TasksLabelRegex.FindStringSubmatch(line.Raw); m != nil {
+		dp.section = sectionTasks
+		if m[1] != "" {
+			dp.appendTask(m[1])
+		}
+		return
+	}
+	if m := SubTypesLabelRegex.FindStringSubmatch(line.Raw); m != nil {
+		dp.section = sectionSubTypes
+		dp.appendSubTypes(line, m[1])
+		return
+	}
+
+	switch dp.section {
+	case sectionName:
+		name, green, digital := splitMarker(line.Text)
+		dp.current.Name = dp.normalizer.Normalize(name)
+		dp.current.Green, dp.current.Digital = green, digital
+		dp.section = sectionDefinition
+	case sectionDefinition:
+		dp.appendText(line.Raw)
+	case sectionTasks:
+		dp.appendTask(line.Raw)
+	case sectionSubTypes:
+		dp.appendSubTypes(line, line.Raw)
+	}
+}
+
+// start 开始一个细类的描述，名称为空时取下一行
+func (dp *descriptionParser) start(line descriptionLine, seq, name string) {
+	dp.current = &model.OccupationNode{
+		Seq:    seq,
+		Level:  4,
+		Source: dp.source(line.row, line.col),
+	}
+	dp.section = sectionDefinition
+	dp.definition = nil
+	dp.tasks = nil
+	dp.pendingSubTypes = nil
+
+	name, dp.current.Green, dp.current.Digital = splitMarker(name)
+	dp.current.Name = dp.normalizer.Normalize(name)
+	if dp.current.Name == "" {
+		dp.section = sectionName
+	}
+}
+
+func (dp *descriptionParser) flush() {
+	if dp.current == nil {
+		return
+	}
+
+	dp.current.Definition = cleanText(strings.Join(dp.definition, ""))
+	for _, task := range dp.tasks {
+		if task = strings.TrimRight(cleanText(task), "；;，,"); task != "" {
+			dp.current.Tasks = append(dp.current.Tasks, task)
+		}
+	}
+	dp.details = append(dp.details, dp.current)
+	dp.subTypes = append(dp.subTypes, dp.numberSubTypes()...)
+	fmt.Printf("Line %-3d found description: %-12s %s (%d tasks)\n",
+		dp.current.Source.Row, dp.current.Seq, dp.current.Name, len(dp.current.Tasks))
+
+	dp.current = nil
+	dp.section = sectionNone
+}
+
+func (dp *descriptionParser) appendText(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		dp.definition = append(dp.definition, text)
+	}
+}
+
+// appendTask 带序号的行开始新任务，其余行续接到上一条任务
+func (dp *descriptionParser) appendTask(text string) {
+	if m := TaskItemRegex.FindStringSubmatch(text); m != nil || len(dp.tasks) == 0 {
+		if m != nil {
+			text = m[1]
+		}
+		dp.tasks = append(dp.tasks, text)
+		return
+	}
+	dp.tasks[len(dp.tasks)-1] += text
+}
+
+// appendSubTypes 拆分一行中的多个工种名称，编号在 flush 时确定
+func (dp *descriptionParser) appendSubTypes(line descriptionLine, text string) {
+	for _, name := range subTypeSeparatorRegex.Split(text, -1) {
+		dp.addSubType(line, "", name)
+	}
+}
+
+// addSubType 记录一个工种，已标编号但不属于当前细类的工种不入库，记入跳过的行
+func (dp *descriptionParser) addSubType(line descriptionLine, seq, name string) {
+	name = dp.normalizer.Normalize(name)
+	if name == "" {
+		return
+	}
+
+	if seq != "" && !strings.HasPrefix(seq, dp.current.Seq+"-") {
+		reason := fmt.Sprintf("sub-type %s %s does not belong to detail %s", seq, name, dp.current.Seq)
+		fmt.Printf("Warning: Line %d, %s (SKIPPED)\n", line.row.Num, reason)
+		dp.report.SkippedRows = append(dp.report.SkippedRows, SkippedRow{
+			File:   dp.file,
+			Sheet:  dp.sheet,
+			Row:    line.row.Num,
+			Rule:   "sub_type_code",
+			Reason: reason,
+		})
+		return
+	}
+
+	dp.pendingSubTypes = append(dp.pendingSubTypes, &model.OccupationNode{
+		Seq:    seq,
+		Name:   name,
+		Level:  5,
+		Source: dp.source(line.row, line.col),
+	})
+}
+
+// numberSubTypes 为未标编号的工种按出现顺序编号，从已标编号的最大序号之后开始，避免与已有编号冲突
+func (dp *descriptionParser) numberSubTypes() []*model.OccupationNode {
+	prefix := dp.current.Seq + "-"
+	next := 1
+	for _, node := range dp.pendingSubTypes {
+		if n, err := strconv.Atoi(strings.TrimPrefix(node.Seq, prefix)); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+
+	for _, node := range dp.pendingSubTypes {
+		if node.Seq == "" {
+			node.Seq = fmt.Sprintf("%s%02d", prefix, next)
+			next++
+		}
+	}
+
+	subTypes := dp.pendingSubTypes
+	dp.pendingSubTypes = nil
+	return subTypes
+}
+
+// cleanText 拼接 OCR 拆开的段落，删除汉字之间插入的空白，仅保留拉丁字母和数字之间的单个空格
+func cleanText(text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if !unicode.IsSpace(runes[i]) {
+			b.WriteRune(runes[i])
+			continue
+		}
+		j := i
+		for j < len(runes) && unicode.IsSpace(runes[j]) {
+			j++
+		}
+		if i > 0 && j < len(runes) && isLatinOrDigit(runes[i-1]) && isLatinOrDigit(runes[j]) {
+			b.WriteRune(' ')
+		}
+		i = j - 1
+	}
+	return b.String()
+}
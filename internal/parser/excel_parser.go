@@ -1,135 +1,348 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"github.com/solisamicus/occstructor/internal/config"
 	"github.com/solisamicus/occstructor/internal/model"
 	"github.com/xuri/excelize/v2"
-	"log"
+	"path"
 	"strings"
+	"sync"
+	"time"
 )
 
+// ExcelParser 构造后不再修改，可在多个 goroutine 中同时解析不同文件
 type ExcelParser struct {
-	config    *config.Config
-	llmClient *LLMClient
+	config     *config.Config
+	merger     NameMerger
+	normalizer *NameNormalizer
+	mismatch   MismatchSink
+	skipRules  []*skipRule
 }
 
-func NewExcelParser(cfg *config.Config) *ExcelParser {
-	return &ExcelParser{
-		config:    cfg,
-		llmClient: NewLLMClient(cfg),
+// NewExcelParser 创建解析器，sink 为 nil 时丢弃不匹配记录
+func NewExcelParser(cfg *config.Config, sink MismatchSink) (*ExcelParser, error) {
+	if sink == nil {
+		sink = discardSink{}
+	}
+
+	normalizer, err := NewNameNormalizer(cfg)
+	if err != nil {
+		return nil, err
 	}
+
+	merger, err := NewNameMerger(cfg, normalizer)
+	if err != nil {
+		return nil, err
+	}
+
+	skipRules, err := compileSkipRules(cfg.Excel.SkipRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExcelParser{
+		config:     cfg,
+		merger:     merger,
+		normalizer: normalizer,
+		mismatch:   sink,
+		skipRules:  skipRules,
+	}, nil
+}
+
+// sheetParser 单个工作表的解析状态，每次解析单独创建
+type sheetParser struct {
+	*ExcelParser
+	file      string
+	sheet     string
+	layout    columnLayout
+	majorRows map[int]bool
+	report    *ParseReport
 }
 
-func KeepOnlyChinese(text string) string {
-	return strings.Join(ChineseRegex.FindAllString(text, -1), "")
+// sheetRow 过滤后的一行数据，Num 为原始 Excel 行号(从 1 开始)，
+// Cells 为编码规范化后的文本，Raw 为单元格原文
+type sheetRow struct {
+	Num   int
+	Cells []string
+	Raw   []string
+}
+
+// ParseFiles 依次解析多个分卷文件并合并结果
+func (p *ExcelParser) ParseFiles(filepaths ...string) (*model.ParseResult, *ParseReport, error) {
+	result := &model.ParseResult{}
+	report := newParseReport(filepaths...)
+	report.SkipRules = p.newSkipRuleHits()
+
+	for _, file := range filepaths {
+		fileResult, fileReport, err := p.ParseFile(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		result.Merge(fileResult)
+		report.merge(fileReport)
+	}
+
+	reportConflicts(result.Conflicts)
+	printSkipRuleHits(report.SkipRules)
+
+	result.SetEdition(p.config.Edition)
+	report.Edition = p.config.Edition
+
+	if p.config.GBM.DeriveDetails {
+		report.DerivedGBM = result.DeriveDetailGBM()
+		fmt.Printf("Derived GBM for %d detail occupations\n", report.DerivedGBM)
+	}
+
+	report.finish(result)
+
+	return result, report, nil
 }
 
-func (p *ExcelParser) ParseFile(filepath string) (*model.ParseResult, error) {
+// ParseFile 解析文件中所有选中的工作表并合并结果
+func (p *ExcelParser) ParseFile(filepath string) (*model.ParseResult, *ParseReport, error) {
 	f, err := excelize.OpenFile(filepath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer f.Close()
 
-	rows, err := f.GetRows(f.GetSheetList()[0])
+	sheets := p.selectSheets(f.GetSheetList())
+	if len(sheets) == 0 {
+		return nil, nil, fmt.Errorf("no sheet matches the include/exclude patterns")
+	}
+
+	configured, err := configuredLayout(p.config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	cleanRows := p.filterRows(rows)
+	result := &model.ParseResult{}
+	report := newParseReport(filepath)
+	report.SkipRules = p.newSkipRuleHits()
 
-	return &model.ParseResult{
-		Majors:    p.findMajors(cleanRows),
-		Middles:   p.findMiddles(cleanRows),
-		Minors:    p.findMinors(cleanRows),
-		SubMinors: p.findSubMinors(cleanRows),
-	}, nil
+	for _, sheet := range sheets {
+		fmt.Printf("Parsing sheet: %s\n", sheet)
+		started := time.Now()
+
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read sheet %s: %w", sheet, err)
+		}
+
+		sp := &sheetParser{
+			ExcelParser: p,
+			file:        filepath,
+			sheet:       sheet,
+			layout:      configured,
+			majorRows:   make(map[int]bool),
+			report:      report,
+		}
+		cleanRows := sp.filterRows(rows)
+
+		if matchAny(p.config.Excel.DescriptionSheets, sheet) {
+			details, subTypes := sp.parseDescriptions(cleanRows)
+			result.Merge(&model.ParseResult{SubTypes: subTypes, Descriptions: details})
+
+			report.Sheets = append(report.Sheets, SheetReport{
+				File:       filepath,
+				Sheet:      sheet,
+				Rows:       len(rows),
+				Layout:     "description",
+				DurationMS: time.Since(started).Milliseconds(),
+			})
+			continue
+		}
+
+		origin := "configured"
+		if p.config.Excel.Columns.AutoDetect {
+			sp.layout = detectLayout(cleanRows, configured)
+			origin = "auto-detected"
+		}
+		fmt.Printf("Column layout (%s): %s\n", origin, sp.layout)
+
+		result.Merge(&model.ParseResult{
+			Majors:    sp.findMajors(cleanRows),
+			Middles:   sp.findMiddles(cleanRows),
+			Minors:    sp.findMinors(cleanRows),
+			SubMinors: sp.findSubMinors(cleanRows),
+		})
+
+		report.Sheets = append(report.Sheets, SheetReport{
+			File:       filepath,
+			Sheet:      sheet,
+			Rows:       len(rows),
+			Layout:     sp.layout.String(),
+			DurationMS: time.Since(started).Milliseconds(),
+		})
+	}
+
+	report.finish(result)
+
+	return result, report, nil
 }
 
-var majorRows map[int]bool
+// selectSheets 按配置中的通配符筛选工作表，未配置 include 时选中全部
+func (p *ExcelParser) selectSheets(sheets []string) []string {
+	var selected []string
 
-func (p *ExcelParser) filterRows(rows [][]string) [][]string {
-	var cleanRows [][]string
+	for _, sheet := range sheets {
+		if len(p.config.Excel.IncludeSheets) > 0 && !matchAny(p.config.Excel.IncludeSheets, sheet) {
+			continue
+		}
+		if matchAny(p.config.Excel.ExcludeSheets, sheet) {
+			continue
+		}
+		selected = append(selected, sheet)
+	}
 
-	for _, row := range rows {
+	return selected
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func reportConflicts(conflicts []*model.SeqConflict) {
+	for _, c := range conflicts {
+		switch {
+		case c.SameName && c.SameGBM:
+			fmt.Printf("Warning: duplicate seq %s at %s (already at %s, same name - dropped)\n",
+				c.Seq, c.Dropped.Source, c.Kept.Source)
+		case c.SameName:
+			fmt.Printf("Warning: conflicting seq %s at %s: GBM %q vs %q at %s (kept first)\n",
+				c.Seq, c.Dropped.Source, c.Dropped.GBM, c.Kept.GBM, c.Kept.Source)
+		default:
+			fmt.Printf("Warning: conflicting seq %s at %s: %q vs %q at %s (kept first)\n",
+				c.Seq, c.Dropped.Source, c.Dropped.Name, c.Kept.Name, c.Kept.Source)
+		}
+	}
+}
+
+func (p *sheetParser) filterRows(rows [][]string) []sheetRow {
+	var cleanRows []sheetRow
+
+	for i, row := range rows {
 		if len(row) == 0 {
 			continue
 		}
-		// skip rows related to the continuation table
-		if p.isHeaderOrContinuationRow(row) {
+		// 跳过表头、续表等非数据行
+		if rule, text := p.skipRow(i+1, row); rule >= 0 {
+			hits := &p.report.SkipRules[rule]
+			hits.Hits++
+			if p.config.Logging.Level == "debug" {
+				fmt.Printf("Line %-3d skipped by rule %s (%s %q): %q\n", i+1, hits.Name, hits.Type, hits.Pattern, text)
+			}
+			p.report.SkippedRows = append(p.report.SkippedRows, SkippedRow{
+				File:   p.file,
+				Sheet:  p.sheet,
+				Row:    i + 1,
+				Rule:   hits.Name,
+				Reason: fmt.Sprintf("%s %q matched %q", hits.Type, hits.Pattern, text),
+			})
 			continue
 		}
-		cleanRows = append(cleanRows, row)
+		cleanRows = append(cleanRows, p.normalizeRow(i+1, row))
 	}
 	return cleanRows
 }
 
-func (p *ExcelParser) isHeaderOrContinuationRow(row []string) bool {
-	if len(row) == 0 {
-		return true
+// normalizeRow 规范化一行中的编码字符并打印每个单元格的替换，Raw 保留原文
+func (p *sheetParser) normalizeRow(num int, cells []string) sheetRow {
+	row := sheetRow{Num: num, Cells: cells, Raw: cells}
+	if !p.config.Normalize.Codes {
+		return row
 	}
 
-	skipPatterns := []string{
-		"分类体系表",
-		"中华人民共和国",
-		"职 业 分 类 大 典",
-		"中类",
-		"续表",
+	row.Cells = make([]string, len(cells))
+	for col, text := range cells {
+		normalized, subs := normalizeCodes(text)
+		row.Cells[col] = normalized
+		for _, sub := range subs {
+			column, _ := excelize.ColumnNumberToName(col + 1)
+			fmt.Printf("Line %-3d normalized %s%d: %s\n", num, column, num, sub)
+			p.report.Substitutions = append(p.report.Substitutions, SubstitutionRecord{
+				File:             p.file,
+				Sheet:            p.sheet,
+				Cell:             fmt.Sprintf("%s%d", column, num),
+				CodeSubstitution: sub,
+			})
+		}
 	}
 
-	for _, cell := range row {
-		cellText := strings.TrimSpace(cell)
-		for _, pattern := range skipPatterns {
-			if strings.Contains(cellText, pattern) {
-				return true
-			}
-		}
+	return row
+}
+
+// source 记录节点来源的工作表、行号、列号及单元格原文
+func (p *sheetParser) source(row sheetRow, col int) *model.Provenance {
+	column, err := excelize.ColumnNumberToName(col + 1)
+	if err != nil {
+		column = ""
 	}
 
-	return false
+	return &model.Provenance{
+		File:    p.file,
+		Sheet:   p.sheet,
+		Row:     row.Num,
+		Column:  column,
+		RawText: rawCell(row, col),
+	}
 }
 
-func (p *ExcelParser) findMajors(rows [][]string) []*model.OccupationNode {
+func (p *sheetParser) findMajors(rows []sheetRow) []*model.OccupationNode {
 	var majors []*model.OccupationNode
-	majorRows = make(map[int]bool)
 
-	for i, row := range rows {
-		if len(row) == 0 {
+	for _, row := range rows {
+		line := strings.Join(strings.Fields(cell(row, p.layout.Major)), "")
+		if line == "" {
 			continue
 		}
 
-		line := strings.Join(strings.Fields(row[0]), "")
-
 		if matches := MajorRegex.FindStringSubmatch(line); matches != nil {
+			seq := p.resolveMajorSeq(row, matches[1], matches[2], matches[3])
+			if seq == "" {
+				reason := fmt.Sprintf("cannot determine major seq from ordinal %q and GBM %q", matches[1], matches[3])
+				fmt.Printf("Warning: Line %d, %s (SKIPPED)\n", row.Num, reason)
+				p.report.SkippedRows = append(p.report.SkippedRows, SkippedRow{
+					File:   p.file,
+					Sheet:  p.sheet,
+					Row:    row.Num,
+					Rule:   "major_seq",
+					Reason: reason,
+				})
+				continue
+			}
 			major := &model.OccupationNode{
-				Seq:   matches[2],
-				GBM:   matches[3],
-				Name:  KeepOnlyChinese(matches[4]),
-				Level: 1,
+				Seq:    seq,
+				GBM:    matches[3],
+				Name:   p.normalizer.Normalize(matches[4]),
+				Level:  1,
+				Source: p.source(row, p.layout.Major),
 			}
 			majors = append(majors, major)
-			majorRows[i] = true
-			fmt.Printf("Line %-3d found major: %-3s %-10s %s\n", i+1, major.Seq, major.GBM, major.Name)
+			p.majorRows[row.Num] = true
+			fmt.Printf("Line %-3d found major: %-3s %-10s %s\n", row.Num, major.Seq, major.GBM, major.Name)
 		}
 	}
 
 	return majors
 }
 
-func (p *ExcelParser) findMiddles(rows [][]string) []*model.OccupationNode {
+func (p *sheetParser) findMiddles(rows []sheetRow) []*model.OccupationNode {
 	var middles []*model.OccupationNode
 
-	for i, row := range rows {
-		if len(row) == 0 {
-			continue
-		}
-
-		if majorRows[i] {
+	for _, row := range rows {
+		if p.majorRows[row.Num] {
 			continue
 		}
 
-		line := strings.Join(strings.Fields(row[0]), "")
+		line := strings.Join(strings.Fields(cell(row, p.layout.Middle)), "")
 
 		locs := MiddleRegex.FindAllStringIndex(line, -1)
 		if len(locs) == 0 {
@@ -147,13 +360,14 @@ func (p *ExcelParser) findMiddles(rows [][]string) []*model.OccupationNode {
 			subMatches := MiddleRegex.FindStringSubmatch(substr)
 
 			middle := &model.OccupationNode{
-				Seq:   subMatches[1],
-				GBM:   subMatches[2],
-				Name:  KeepOnlyChinese(strings.TrimPrefix(substr, subMatches[0])),
-				Level: 2,
+				Seq:    subMatches[1],
+				GBM:    subMatches[2],
+				Name:   p.normalizer.Normalize(strings.TrimPrefix(substr, subMatches[0])),
+				Level:  2,
+				Source: p.source(row, p.layout.Middle),
 			}
 
-			fmt.Printf("Line %-3d found middle: %-6s %-10s %s\n", i+1, middle.Seq, middle.GBM, middle.Name)
+			fmt.Printf("Line %-3d found middle: %-6s %-10s %s\n", row.Num, middle.Seq, middle.GBM, middle.Name)
 			middles = append(middles, middle)
 		}
 	}
@@ -161,19 +375,15 @@ func (p *ExcelParser) findMiddles(rows [][]string) []*model.OccupationNode {
 	return middles
 }
 
-func (p *ExcelParser) findMinors(rows [][]string) []*model.OccupationNode {
+func (p *sheetParser) findMinors(rows []sheetRow) []*model.OccupationNode {
 	var minors []*model.OccupationNode
 
-	for i, row := range rows {
-		if len(row) < 3 {
-			continue
-		}
-
-		if majorRows[i] {
+	for _, row := range rows {
+		if p.majorRows[row.Num] {
 			continue
 		}
 
-		line := strings.Join(strings.Fields(row[2]), "")
+		line := strings.Join(strings.Fields(cell(row, p.layout.Minor)), "")
 		if line == "" {
 			continue
 		}
@@ -197,13 +407,14 @@ func (p *ExcelParser) findMinors(rows [][]string) []*model.OccupationNode {
 			}
 
 			minor := &model.OccupationNode{
-				Seq:   subMatches[1],
-				GBM:   subMatches[2],
-				Name:  KeepOnlyChinese(strings.TrimPrefix(substr, subMatches[0])),
-				Level: 3,
+				Seq:    subMatches[1],
+				GBM:    subMatches[2],
+				Name:   p.normalizer.Normalize(strings.TrimPrefix(substr, subMatches[0])),
+				Level:  3,
+				Source: p.source(row, p.layout.Minor),
 			}
 
-			fmt.Printf("Line %-3d found minor:  %-8s %-10s %s\n", i+1, minor.Seq, minor.GBM, minor.Name)
+			fmt.Printf("Line %-3d found minor:  %-8s %-10s %s\n", row.Num, minor.Seq, minor.GBM, minor.Name)
 			minors = append(minors, minor)
 		}
 	}
@@ -211,86 +422,107 @@ func (p *ExcelParser) findMinors(rows [][]string) []*model.OccupationNode {
 	return minors
 }
 
-var mismatchLogger *MismatchLogger
-
-func init() {
-	var err error
-	mismatchLogger, err = NewMismatchLogger()
-	if err != nil {
-		log.Printf("Failed to create mismatch logger: %v", err)
-	}
-}
-
-func (p *ExcelParser) findSubMinors(rows [][]string) []*model.OccupationNode {
+func (p *sheetParser) findSubMinors(rows []sheetRow) []*model.OccupationNode {
 	var subMinors []*model.OccupationNode
 
-	for i, row := range rows {
-		if len(row) < 5 {
+	records := p.assembleDetails(rows)
+	names := p.mergeNames(records)
+	for i, rec := range records {
+		if rec.kind == recordMerged {
+			subMinors = append(subMinors, p.parseMergedSubMinors(rec.row, rec.codes)...)
 			continue
 		}
+		subMinors = append(subMinors, p.parseSeparatedSubMinors(rec, names[i])...)
+	}
 
-		if majorRows[i] {
-			continue
-		}
+	return subMinors
+}
 
-		codeCol := strings.TrimSpace(row[4])
-		nameCol := ""
-		if len(row) >= 6 {
-			nameCol = strings.TrimSpace(row[5])
-		}
+// mergeNames 用 ai.concurrency 个 worker 并发合并各分列记录的名称，结果按记录顺序返回
+func (p *sheetParser) mergeNames(records []*detailRecord) [][]string {
+	results := make([][]string, len(records))
+	usages := make([]LLMUsage, len(records))
+
+	workers := p.config.AI.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
 
-		if nameCol != "" {
-			nodes := p.parseSeparatedSubMinors(i, codeCol, nameCol)
-			subMinors = append(subMinors, nodes...)
-		} else {
-			nodes := p.parseMergedSubMinors(i, codeCol)
-			subMinors = append(subMinors, nodes...)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				rec := records[i]
+				req := MergeRequest{Text: stripMarkers(rec.names), Codes: strings.Fields(rec.codes)}
+				results[i] = p.merger.MergeNames(context.Background(), req, &usages[i])
+			}
+		}()
+	}
+
+	for i, rec := range records {
+		if rec.kind != recordMerged {
+			jobs <- i
 		}
 	}
+	close(jobs)
+	wg.Wait()
 
-	return subMinors
+	for _, usage := range usages {
+		p.report.LLM.add(usage)
+	}
+	return results
 }
 
-func (p *ExcelParser) parseSeparatedSubMinors(lineNum int, codesText, namesText string) []*model.OccupationNode {
+func (p *sheetParser) parseSeparatedSubMinors(rec *detailRecord, names []string) []*model.OccupationNode {
 	var nodes []*model.OccupationNode
 
+	codesText, namesText := rec.codes, rec.names
 	codes := strings.Fields(codesText)
-	var names []string
-	if p.llmClient != nil {
-		names = p.llmClient.MergeNamesWithLLM(namesText)
-	} else {
-		lines := strings.Split(namesText, "\n")
-		for _, line := range lines {
-			line = KeepOnlyChinese(strings.TrimSpace(line))
-			if line != "" {
-				names = append(names, line)
-			}
-		}
-	}
+	green, digital := assignMarkers(names, extractMarkers(namesText, p.normalizer), p.normalizer)
 
+	row := rec.row
 	if len(codes) != len(names) {
 		fmt.Printf("Warning: Line %d, code count %d != name count %d (SKIPPED - logged)\n",
-			lineNum+1, len(codes), len(names))
-		if mismatchLogger != nil {
-			mismatchLogger.LogMismatch(lineNum+1, codes, names, codesText, namesText)
+			row.Num, len(codes), len(names))
+		column, _ := excelize.ColumnNumberToName(p.layout.DetailName + 1)
+		mismatch := &Mismatch{
+			Time:      time.Now(),
+			File:      p.file,
+			Sheet:     p.sheet,
+			Line:      row.Num,
+			Column:    column,
+			Codes:     codes,
+			Names:     names,
+			RawCodes:  codesText,
+			RawNames:  namesText,
+			Rows:      continuedRows(rec),
+			Suggested: suggestPairing(codes, names, green, digital),
 		}
+		p.mismatch.LogMismatch(mismatch)
+		p.report.Mismatches = append(p.report.Mismatches, mismatch)
 		return nodes
 	}
 
 	for j := 0; j < len(codes); j++ {
 		node := &model.OccupationNode{
-			Seq:   codes[j],
-			Name:  names[j],
-			Level: 4,
+			Seq:     codes[j],
+			Name:    names[j],
+			Level:   4,
+			Green:   green[j],
+			Digital: digital[j],
+			Source:  p.source(row, p.layout.DetailName),
 		}
-		fmt.Printf("Line %-3d found sub-minor (separated): %-12s %s\n", lineNum+1, node.Seq, node.Name)
+		fmt.Printf("Line %-3d found sub-minor (separated): %-12s %s%s\n", row.Num, node.Seq, node.Name, node.MarkerSuffix())
 		nodes = append(nodes, node)
 	}
 
 	return nodes
 }
 
-func (p *ExcelParser) parseMergedSubMinors(lineNum int, mergedText string) []*model.OccupationNode {
+func (p *sheetParser) parseMergedSubMinors(row sheetRow, mergedText string) []*model.OccupationNode {
 	var nodes []*model.OccupationNode
 
 	cleanedText := strings.Join(strings.Fields(mergedText), "")
@@ -305,22 +537,19 @@ func (p *ExcelParser) parseMergedSubMinors(lineNum int, mergedText string) []*mo
 
 		substr := cleanedText[start:end]
 		seq := DetailCodeRegex.FindString(substr)
-		name := KeepOnlyChinese(strings.TrimPrefix(substr, seq))
+		rest, green, digital := splitMarker(strings.TrimPrefix(substr, seq))
 
 		node := &model.OccupationNode{
-			Seq:   DetailCodeRegex.FindString(substr),
-			Name:  name,
-			Level: 4,
+			Seq:     seq,
+			Name:    p.normalizer.Normalize(rest),
+			Level:   4,
+			Green:   green,
+			Digital: digital,
+			Source:  p.source(row, p.layout.DetailCode),
 		}
-		fmt.Printf("Line %-3d found sub-minor (merged): %-12s %s\n", lineNum+1, node.Seq, node.Name)
+		fmt.Printf("Line %-3d found sub-minor (merged): %-12s %s%s\n", row.Num, node.Seq, node.Name, node.MarkerSuffix())
 		nodes = append(nodes, node)
 	}
 
 	return nodes
 }
-
-func (p *ExcelParser) CloseLogger() {
-	if mismatchLogger != nil {
-		mismatchLogger.Close()
-	}
-}
@@ -0,0 +1,472 @@
+package parser_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/parser"
+	"github.com/xuri/excelize/v2"
+)
+
+type recordingSink struct {
+	mu    sync.Mutex
+	lines []int
+}
+
+func (s *recordingSink) LogMismatch(m *parser.Mismatch) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, m.Line)
+}
+
+func writeWorkbook(t *testing.T, rows [][]string) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetList()[0]
+	for i, row := range rows {
+		cellName, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make([]interface{}, len(row))
+		for j, v := range row {
+			values[j] = v
+		}
+		if err := f.SetSheetRow(sheet, cellName, &values); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func firstVolume(t *testing.T) string {
+	return writeWorkbook(t, [][]string{
+		{"分类体系表"},
+		{"第一大类    1 (GBM10) 党的机关、  国家机关、  群众团体和社会组织、  企事业单位负责人"},
+		{"中类", "", "小类", "", "细类  (职业)"},
+		{
+			"      1-01 ( GBM 10100)  中国\n共产 党 机 关 和 基 层 组 织 负\n责人",
+			"",
+			"      1-01-00 (GBM 10100)  中国\n共产党机关和基层组织负责人",
+			"",
+			"1-01-00-01\n\n1-01-00-02",
+			"中国共产党机关负责人\n中国共产党基层组织负责人",
+		},
+	})
+}
+
+func secondVolume(t *testing.T) string {
+	return writeWorkbook(t, [][]string{
+		{"第二大类    2 (GBM20000) 专业技术人员"},
+		{"中类", "", "小类", "", "细类  (职业)"},
+		{
+			"      2-02 ( GBM 20200)  工程\n技术人员",
+			"",
+			"      2-02-10 (GBM 20210)  信息\n和通信工程技术人员",
+			"",
+			"2-02-10-03\n\n2-02-10-04\n\n2-02-10-05",
+			"计算机软件工程\n技术人员 S\n计算机网络工程\n技术人员 S",
+		},
+		{"", "", "", "", "2-02-10-06 信息系统分析工程技术人员 S 2-02-10-07 嵌入式系统设计工程技术人员 L/S"},
+	})
+}
+
+func TestParseFileConcurrent(t *testing.T) {
+	first, second := firstVolume(t), secondVolume(t)
+
+	sink := &recordingSink{}
+	p, err := parser.NewExcelParser(&config.Config{}, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const rounds = 8
+	results := make([]*model.ParseResult, rounds)
+	errs := make([]error, rounds)
+
+	var wg sync.WaitGroup
+	for i := 0; i < rounds; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := first
+			if i%2 == 1 {
+				path = second
+			}
+			results[i], _, errs[i] = p.ParseFile(path)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatalf("round %d: %v", i, errs[i])
+		}
+		if i%2 == 0 {
+			if len(result.Majors) != 1 || len(result.Middles) != 1 || len(result.Minors) != 1 || len(result.SubMinors) != 2 {
+				t.Errorf("round %d: unexpected counts %d/%d/%d/%d", i,
+					len(result.Majors), len(result.Middles), len(result.Minors), len(result.SubMinors))
+			}
+			continue
+		}
+
+		if len(result.SubMinors) != 2 {
+			t.Fatalf("round %d: got %d sub-minors, want 2 from the merged row", i, len(result.SubMinors))
+		}
+		last := result.SubMinors[1]
+		if last.Seq != "2-02-10-07" || !last.Green || !last.Digital {
+			t.Errorf("round %d: got %+v, want 2-02-10-07 marked L/S", i, last)
+		}
+		if last.Source == nil || last.Source.Row != 4 || last.Source.Column != "E" {
+			t.Errorf("round %d: got source %v, want E4", i, last.Source)
+		}
+	}
+
+	// 第二卷的分列行有 3 个代码、2 个名称，每轮记录一次
+	if len(sink.lines) != rounds/2 {
+		t.Errorf("got %d mismatches, want %d", len(sink.lines), rounds/2)
+	}
+	for _, line := range sink.lines {
+		if line != 3 {
+			t.Errorf("mismatch reported at line %d, want 3", line)
+		}
+	}
+}
+
+func TestParseFileNormalizesCodes(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"第二大类    ２ （GBM2OOOO） 专业技术人员"},
+		{
+			"      2－02 （ GBM 2O2OO）  工程\n技术人员",
+			"",
+			"      2–O2–10 (GBM 2021O)  信息\n和通信工程技术人员",
+			"",
+			"2-02-l0-03\n\n2 — 02 — 10 — 04",
+			"计算机软件工程技术人员 Ｓ\n计算机网络工程技术人员（含５Ｇ）：",
+		},
+	})
+
+	p, err := parser.NewExcelParser(config.DefaultConfig(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, nodes := range [][]*model.OccupationNode{result.Majors, result.Middles, result.Minors, result.SubMinors} {
+		for _, node := range nodes {
+			got = append(got, node.Seq+" "+node.GBM)
+		}
+	}
+	want := []string{"2 20000", "2-02 20200", "2-02-10 20210", "2-02-10-03 ", "2-02-10-04 "}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if report.Counts != (parser.LevelCounts{Major: 1, Middle: 1, Minor: 1, Detail: 2}) {
+		t.Errorf("got counts %+v", report.Counts)
+	}
+	ocr := 0
+	for _, sub := range report.Substitutions {
+		if sub.Reason == "ocr" {
+			ocr += sub.Count
+		}
+	}
+	if ocr != 15 {
+		t.Errorf("got %d OCR substitutions, want 15: %+v", ocr, report.Substitutions)
+	}
+	// 名称单元格不做编码规范化
+	for _, sub := range report.Substitutions {
+		if sub.Cell == "F2" {
+			t.Errorf("got substitution %s in a name cell", sub.CodeSubstitution)
+		}
+	}
+
+	if detail := result.SubMinors[0]; !detail.Digital || detail.Source.RawText != "计算机软件工程技术人员 Ｓ\n计算机网络工程技术人员（含５Ｇ）：" {
+		t.Errorf("got %+v, want digital marker and raw source text", detail)
+	}
+}
+
+func TestParseFileDescriptions(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"2-02-10 (GBM 20210) 信息和通信工程技术人员"},
+		{"2-02-10-03  计算机软件工程技术人员 S"},
+		{"定义：从事计算机软件需求分析、设计、开发、测\n试的工 程技术人员。"},
+		{"主要工作任务：\n1. 分析用户需求，编写 CAD 软件需求规格说明；\n2. 设计软件架构，\n编写设计文档；\n（3）测试软件。"},
+		{"本职业包括下列工种：\n软件开发工程技术员、软件测试工程技术员"},
+		{"2-02-10-04"},
+		{"计算机网络工程技术人员"},
+		{"主要工作任务：", "1、规划网络。"},
+		{"下列工种归入本职业：\n2-02-10-04-03 网络运维员"},
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Excel.DescriptionSheets = []string{"Sheet*"}
+	p, err := parser.NewExcelParser(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Counts.Descriptions != 2 || report.Counts.SubType != 3 {
+		t.Fatalf("got counts %+v", report.Counts)
+	}
+
+	software := result.Descriptions[0]
+	if software.Seq != "2-02-10-03" || software.Name != "计算机软件工程技术人员" || !software.Digital {
+		t.Errorf("got %+v", software)
+	}
+	if software.Definition != "从事计算机软件需求分析、设计、开发、测试的工程技术人员。" {
+		t.Errorf("got definition %q", software.Definition)
+	}
+	wantTasks := []string{"分析用户需求，编写CAD软件需求规格说明", "设计软件架构，编写设计文档", "测试软件。"}
+	if strings.Join(software.Tasks, "|") != strings.Join(wantTasks, "|") {
+		t.Errorf("got tasks %q", software.Tasks)
+	}
+
+	network := result.Descriptions[1]
+	if network.Name != "计算机网络工程技术人员" || len(network.Tasks) != 1 || network.Tasks[0] != "规划网络。" {
+		t.Errorf("got %+v", network)
+	}
+
+	var subTypes []string
+	for _, node := range result.SubTypes {
+		subTypes = append(subTypes, node.Seq+" "+node.Name)
+	}
+	want := []string{"2-02-10-03-01 软件开发工程技术员", "2-02-10-03-02 软件测试工程技术员", "2-02-10-04-03 网络运维员"}
+	if strings.Join(subTypes, "|") != strings.Join(want, "|") {
+		t.Errorf("got sub-types %q", subTypes)
+	}
+
+	// 表格中没有的细类以描述新增，工种挂在细类下
+	nodes := result.BuildHierarchy()
+	if len(nodes) != 5 || *nodes[4].ParentSeq != "2-02-10-04" || nodes[4].Level != 5 {
+		t.Errorf("got %d nodes, last %+v", len(nodes), nodes[len(nodes)-1])
+	}
+}
+
+func TestParseFileSubTypeNumbering(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"2-02-10-05 信息系统分析工程技术人员"},
+		{"下列工种归入本职业：\n2-02-10-05-03 系统架构设计员\n需求分析员、系统测试员\n2-02-10-05-01 系统规划员\n2-02-10-06-01 嵌入式开发员"},
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Excel.DescriptionSheets = []string{"Sheet*"}
+	p, err := parser.NewExcelParser(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subTypes []string
+	for _, node := range result.SubTypes {
+		subTypes = append(subTypes, node.Seq+" "+node.Name)
+	}
+	// 未标编号的工种排在已标编号的最大序号之后
+	want := []string{"2-02-10-05-03 系统架构设计员", "2-02-10-05-04 需求分析员", "2-02-10-05-05 系统测试员", "2-02-10-05-01 系统规划员"}
+	if strings.Join(subTypes, "|") != strings.Join(want, "|") {
+		t.Errorf("got sub-types %q, want %q", subTypes, want)
+	}
+
+	if len(report.SkippedRows) != 1 || report.SkippedRows[0].Rule != "sub_type_code" || !strings.Contains(report.SkippedRows[0].Reason, "2-02-10-06-01") {
+		t.Errorf("got skipped rows %+v, want the sub-type of another detail", report.SkippedRows)
+	}
+}
+
+func TestParseFileMajorOrdinal(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"第 三 大 类    3 (GBM30000) 办事人员和有关人员"},
+		{"第4大类 (GBM40000) 社会生产服务和生活服务人员"},
+		{"第五大类    6 (GBM50000) 农、林、牧、渔业生产及辅助人员"},
+		{"第十大类    8 (GBM80000) 不便分类的其他从业人员"},
+		{"第囗大类 (GBM70000) 军人"},
+		{"第口大类 (GBMXXXXX) 无法识别"},
+	})
+
+	p, err := parser.NewExcelParser(config.DefaultConfig(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, node := range result.Majors {
+		got = append(got, node.Seq+" "+node.Name)
+	}
+	want := []string{"3 办事人员和有关人员", "4 社会生产服务和生活服务人员", "5 农、林、牧、渔业生产及辅助人员", "8 不便分类的其他从业人员", "7 军人"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// 第五大类与编号 6 不一致，GBM 首位支持序数；第十大类与编号、GBM 均不一致，保留编号；
+	// 序数无法识别且没有编号时采用 GBM 首位，GBM 也不可用时跳过该行并记入报告
+	if len(report.OrdinalMismatches) != 4 {
+		t.Fatalf("got %+v, want 4 ordinal mismatches", report.OrdinalMismatches)
+	}
+	if m := report.OrdinalMismatches[0]; m.Row != 3 || m.Seq != "6" || m.Used != "5" {
+		t.Errorf("got %+v", m)
+	}
+	if m := report.OrdinalMismatches[1]; m.Row != 4 || m.Seq != "8" || m.Used != "8" {
+		t.Errorf("got %+v", m)
+	}
+	if m := report.OrdinalMismatches[2]; m.Row != 5 || m.Seq != "" || m.Used != "7" {
+		t.Errorf("got %+v", m)
+	}
+	if m := report.OrdinalMismatches[3]; m.Row != 6 || m.Used != "" {
+		t.Errorf("got %+v", m)
+	}
+	if len(report.SkippedRows) != 1 || report.SkippedRows[0].Row != 6 || report.SkippedRows[0].Rule != "major_seq" {
+		t.Errorf("got skipped rows %+v, want row 6", report.SkippedRows)
+	}
+
+	for text, want := range map[string]int{"一": 1, "八": 8, "十": 10, "十二": 12, "二十": 20, "二十一": 21, "８": 8, "8": 8} {
+		if n, ok := parser.ParseOrdinal(text); !ok || n != want {
+			t.Errorf("ParseOrdinal(%q) = %d, %v, want %d", text, n, ok, want)
+		}
+	}
+	for _, text := range []string{"", "零", "十十", "大", "0"} {
+		if n, ok := parser.ParseOrdinal(text); ok {
+			t.Errorf("ParseOrdinal(%q) = %d, want failure", text, n)
+		}
+	}
+}
+
+func TestParseFileContinuations(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"第二大类    2 (GBM20000) 专业技术人员"},
+		{
+			"2-02 (GBM 20200) 工程技术人员", "",
+			"2-02-10 (GBM 20210) 信息和通信工程技术人员", "",
+			"2-02-10-03\n2-02-10-04",
+			"计算机软件工程技术人员\n计算机网络工",
+		},
+		{"续表"},
+		{"", "", "", "", "", "程技术人员"},
+		{"", "", "2-02-11 (GBM 20211) 电子工程技术人员", "", "2-02-11-01\n2-02-11-02"},
+		{"", "", "", "", "", "电子材料工程技术人员\n电子元器件工程技术人员"},
+		{"", "", "", "", "", "广播电视工程技术人员"},
+		{"", "", "", "", "2-02-12-01"},
+	})
+
+	p, err := parser.NewExcelParser(config.DefaultConfig(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, node := range result.SubMinors {
+		got = append(got, fmt.Sprintf("%s %s@%d", node.Seq, node.Name, node.Source.Row))
+	}
+	want := []string{
+		"2-02-10-03 计算机软件工程技术人员@2",
+		"2-02-10-04 计算机网络工程技术人员@2",
+		"2-02-11-01 电子材料工程技术人员@5",
+		"2-02-11-02 电子元器件工程技术人员@5",
+		"2-02-12-01 广播电视工程技术人员@7",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if len(report.Mismatches) != 0 {
+		t.Errorf("got mismatches %+v", report.Mismatches)
+	}
+	var reasons []string
+	for _, c := range report.Continuations {
+		reasons = append(reasons, fmt.Sprintf("%d>%d %s", c.Row, c.Into, c.Reason))
+	}
+	if want := "4>2 name fragment|6>5 names for codes|8>7 codes for names"; strings.Join(reasons, "|") != want {
+		t.Errorf("got continuations %q, want %q", reasons, want)
+	}
+}
+
+func TestParseFileSkipRules(t *testing.T) {
+	path := writeWorkbook(t, [][]string{
+		{"封面"},
+		{"第二大类    2 (GBM20000) 专业技术人员"},
+		{"中 类", "", "小类", "", "细类  (职业)"},
+		{
+			"2-02 (GBM 20200) 工程技术人员", "",
+			"2-02-10 (GBM 20210) 信息和通信工程技术人员", "",
+			"2-02-10-03", "中类样品检验员",
+		},
+		{"", "", "", "", "", "", "第 12 页"},
+		{"续表"},
+		{"职 业 分 类 大 典"},
+		{"续表（二）"},
+		{"", "", "", "", "续表 1"},
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Excel.SkipRules = append(cfg.Excel.SkipRules,
+		config.SkipRule{Name: "cover", Type: config.SkipRow, Rows: []int{1}},
+		config.SkipRule{Name: "footer", Type: config.SkipRegex, Pattern: `^第\s*\d+\s*页$`, Column: "G"},
+	)
+	p, err := parser.NewExcelParser(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 名称中含 "中类" 的数据行不应被跳过
+	if len(result.SubMinors) != 1 || result.SubMinors[0].Name != "中类样品检验员" {
+		t.Errorf("got %+v, want the detail row kept", result.SubMinors)
+	}
+
+	hits := make(map[string]int)
+	for _, h := range report.SkipRules {
+		hits[h.Name] = h.Hits
+	}
+	want := map[string]int{"title": 0, "page_header": 1, "column_header": 1, "continuation": 3, "cover": 1, "footer": 1}
+	for name, n := range want {
+		if hits[name] != n {
+			t.Errorf("rule %s: got %d hits, want %d", name, hits[name], n)
+		}
+	}
+	if len(report.SkippedRows) != 7 || report.SkippedRows[0].Rule != "cover" {
+		t.Errorf("got skipped rows %+v", report.SkippedRows)
+	}
+
+	cfg.Excel.SkipRules = []config.SkipRule{{Name: "bad", Type: "prefix", Pattern: "x"}}
+	if _, err := parser.NewExcelParser(cfg, nil); err == nil {
+		t.Error("expected an error for an unknown skip rule type")
+	}
+
+	cfg.Excel.SkipRules = []config.SkipRule{{Name: "empty", Type: config.SkipRegex}}
+	if _, err := parser.NewExcelParser(cfg, nil); err == nil {
+		t.Error("expected an error for a regex skip rule without pattern")
+	}
+}
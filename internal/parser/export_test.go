@@ -0,0 +1,27 @@
+package parser
+
+import "github.com/solisamicus/occstructor/internal/config"
+
+// 供 parser_test 包直接测试的内部函数
+var SplitMarker = splitMarker
+
+// AssignMarkers 按名称列原文为合并后的名称分配 L/S 标识
+func AssignMarkers(names []string, namesText string, normalizer *NameNormalizer) (green, digital []bool) {
+	return assignMarkers(names, extractMarkers(namesText, normalizer), normalizer)
+}
+
+// SheetLayout 返回按配置得到的列布局，开启 auto_detect 时从 rows 中检测
+func SheetLayout(cfg *config.Config, rows [][]string) (string, error) {
+	layout, err := configuredLayout(cfg)
+	if err != nil || !cfg.Excel.Columns.AutoDetect {
+		return layout.String(), err
+	}
+
+	sheetRows := make([]sheetRow, len(rows))
+	for i, cells := range rows {
+		sheetRows[i] = sheetRow{Num: i + 1, Cells: cells, Raw: cells}
+	}
+	return detectLayout(sheetRows, layout).String(), nil
+}
+
+var SuggestPairing = suggestPairing
@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Fixture 一次名称合并的录制结果，fixtures 文件每行一条
+type Fixture struct {
+	Input string   `json:"input"`
+	Names []string `json:"names"`
+}
+
+// LoadFixtures 读取 fixtures 文件，同一输入出现多次时以最后一条为准
+func LoadFixtures(path string) (map[string][]string, error) {
+	fixtures := make(map[string][]string)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixtures: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var f Fixture
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return nil, fmt.Errorf("failed to parse fixtures line %d: %w", line, err)
+		}
+		fixtures[f.Input] = f.Names
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	return fixtures, nil
+}
+
+// RecordingMerger 调用 inner 合并名称，并把每个新输入的模型结果追加到 fixtures 文件；
+// inner 回退到规则处理时不录制，避免回放时把规则结果当作模型结果
+type RecordingMerger struct {
+	path  string
+	inner NameMerger
+
+	mu       sync.Mutex
+	recorded map[string]bool
+}
+
+func NewRecordingMerger(path string, inner NameMerger) (*RecordingMerger, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ai.fixtures is required for provider %s", ProviderRecord)
+	}
+
+	recorded := make(map[string]bool)
+	existing, err := LoadFixtures(path)
+	switch {
+	case err == nil:
+		for input := range existing {
+			recorded[input] = true
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+
+	return &RecordingMerger{path: path, inner: inner, recorded: recorded}, nil
+}
+
+func (m *RecordingMerger) MergeNames(ctx context.Context, req MergeRequest, usage *LLMUsage) []string {
+	fallbacks := usage.Fallbacks
+	names := m.inner.MergeNames(ctx, req, usage)
+	if usage.Fallbacks > fallbacks {
+		return names
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.recorded[req.Text] {
+		return names
+	}
+	if err := m.append(Fixture{Input: req.Text, Names: names}); err != nil {
+		fmt.Println("Warning: failed to record fixture:", err)
+		return names
+	}
+	m.recorded[req.Text] = true
+
+	return names
+}
+
+func (m *RecordingMerger) append(f Fixture) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// ReplayMerger 从 fixtures 文件回放合并结果，不访问网络；没有录制的输入交给 fallback 处理
+type ReplayMerger struct {
+	fixtures map[string][]string
+	fallback NameMerger
+}
+
+func NewReplayMerger(path string, fallback NameMerger) (*ReplayMerger, error) {
+	if path == "" {
+		return nil, fmt.Errorf("ai.fixtures is required for provider %s", ProviderReplay)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReplayMerger{fixtures: fixtures, fallback: fallback}, nil
+}
+
+func (m *ReplayMerger) MergeNames(ctx context.Context, req MergeRequest, usage *LLMUsage) []string {
+	if names, ok := m.fixtures[req.Text]; ok {
+		return names
+	}
+
+	fmt.Printf("Warning: no fixture for %q, using rules\n", req.Text)
+	usage.Fallbacks++
+	return m.fallback.MergeNames(ctx, req, usage)
+}
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/xuri/excelize/v2"
+)
+
+// columnLayout 各字段所在的列(从 0 开始)
+type columnLayout struct {
+	Major      int
+	Middle     int
+	Minor      int
+	DetailCode int
+	DetailName int
+}
+
+var defaultLayout = columnLayout{Major: 0, Middle: 0, Minor: 2, DetailCode: 4, DetailName: 5}
+
+func (l columnLayout) String() string {
+	name := func(col int) string {
+		s, err := excelize.ColumnNumberToName(col + 1)
+		if err != nil {
+			return "?"
+		}
+		return s
+	}
+	return fmt.Sprintf("major=%s middle=%s minor=%s detail_code=%s detail_name=%s",
+		name(l.Major), name(l.Middle), name(l.Minor), name(l.DetailCode), name(l.DetailName))
+}
+
+// configuredLayout 读取配置中的列号，未配置的字段使用默认列
+func configuredLayout(cfg *config.Config) (columnLayout, error) {
+	layout := defaultLayout
+	columns := cfg.Excel.Columns
+
+	fields := []struct {
+		letter string
+		target *int
+	}{
+		{columns.Major, &layout.Major},
+		{columns.Middle, &layout.Middle},
+		{columns.Minor, &layout.Minor},
+		{columns.DetailCode, &layout.DetailCode},
+		{columns.DetailName, &layout.DetailName},
+	}
+
+	for _, f := range fields {
+		if f.letter == "" {
+			continue
+		}
+		col, err := excelize.ColumnNameToNumber(strings.ToUpper(f.letter))
+		if err != nil {
+			return layout, fmt.Errorf("invalid column %q: %w", f.letter, err)
+		}
+		*f.target = col - 1
+	}
+
+	return layout, nil
+}
+
+// detectLayout 按各列匹配中类、小类、细类代码正则的次数选出得分最高的列，
+// 某个字段没有任何匹配时沿用 fallback 中的列
+func detectLayout(rows []sheetRow, fallback columnLayout) columnLayout {
+	width := 0
+	for _, row := range rows {
+		if len(row.Cells) > width {
+			width = len(row.Cells)
+		}
+	}
+
+	majorScore := make([]int, width)
+	middleScore := make([]int, width)
+	minorScore := make([]int, width)
+	codeScore := make([]int, width)
+
+	for _, row := range rows {
+		for col, cell := range row.Cells {
+			text := strings.Join(strings.Fields(cell), "")
+			if text == "" {
+				continue
+			}
+			if MajorRegex.MatchString(text) {
+				majorScore[col]++
+			}
+			minors := len(MinorRegex.FindAllString(text, -1))
+			// 小类代码的后两段同样能被中类正则匹配，需要扣除
+			if middles := len(MiddleRegex.FindAllString(text, -1)) - minors; middles > 0 {
+				middleScore[col] += middles
+			}
+			minorScore[col] += minors
+			codeScore[col] += len(DetailCodeRegex.FindAllString(text, -1))
+		}
+	}
+
+	layout := fallback
+	if col, ok := bestColumn(middleScore); ok {
+		layout.Middle = col
+		layout.Major = col
+	}
+	if col, ok := bestColumn(majorScore); ok {
+		layout.Major = col
+	}
+	if col, ok := bestColumn(minorScore); ok {
+		layout.Minor = col
+	}
+	if col, ok := bestColumn(codeScore); ok {
+		layout.DetailCode = col
+		layout.DetailName = detectNameColumn(rows, col, width)
+	}
+
+	return layout
+}
+
+// detectNameColumn 在细类代码列右侧寻找与代码同行出现汉字最多的列，
+// 找不到时认为名称与代码合并在同一列
+func detectNameColumn(rows []sheetRow, codeCol, width int) int {
+	score := make([]int, width)
+
+	for _, row := range rows {
+		if codeCol >= len(row.Cells) || !DetailCodeRegex.MatchString(row.Cells[codeCol]) {
+			continue
+		}
+		for col := codeCol + 1; col < len(row.Cells); col++ {
+			if ChineseRegex.MatchString(row.Cells[col]) {
+				score[col]++
+			}
+		}
+	}
+
+	if col, ok := bestColumn(score); ok {
+		return col
+	}
+	return codeCol
+}
+
+func bestColumn(scores []int) (int, bool) {
+	best, bestScore := 0, 0
+	for col, score := range scores {
+		if score > bestScore {
+			best, bestScore = col, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// cell 安全读取一行中的单元格，越界时返回空串
+func cell(row sheetRow, col int) string {
+	if col < 0 || col >= len(row.Cells) {
+		return ""
+	}
+	return row.Cells[col]
+}
+
+func rawCell(row sheetRow, col int) string {
+	if col < 0 || col >= len(row.Raw) {
+		return ""
+	}
+	return row.Raw[col]
+}
@@ -0,0 +1,63 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func TestSheetLayout(t *testing.T) {
+	separated := [][]string{
+		{"第二大类 2 (GBM20000) 专业技术人员"},
+		{"2-02 (GBM 20200) 工程技术人员", "", "2-02-10 (GBM 20210) 信息和通信工程技术人员", "", "2-02-10-03\n2-02-10-04", "计算机软件工程技术人员\n计算机网络工程技术人员"},
+		{"", "", "", "", "2-02-10-05", "信息系统分析工程技术人员"},
+	}
+	// 代码和名称在同一单元格
+	merged := [][]string{
+		{"第二大类 2 (GBM20000) 专业技术人员"},
+		{"", "2-02 (GBM 20200) 工程技术人员", "2-02-10 (GBM 20210) 信息和通信工程技术人员", "2-02-10-03 计算机软件工程技术人员\n2-02-10-04 计算机网络工程技术人员"},
+		{"", "", "", "2-02-10-05 信息系统分析工程技术人员"},
+	}
+	// 各列均无法识别，沿用配置的列
+	unknown := [][]string{{"职业分类", "说明"}}
+
+	tests := []struct {
+		name    string
+		columns map[string]string
+		detect  bool
+		rows    [][]string
+		want    string
+	}{
+		{"default", nil, false, separated, "major=A middle=A minor=C detail_code=E detail_name=F"},
+		{"configured", map[string]string{"minor": "b", "detail_code": "C", "detail_name": "D"}, false, merged, "major=A middle=A minor=B detail_code=C detail_name=D"},
+		{"detect separated", nil, true, separated, "major=A middle=A minor=C detail_code=E detail_name=F"},
+		{"detect merged", nil, true, merged, "major=A middle=B minor=C detail_code=D detail_name=D"},
+		{"detect falls back to configured", map[string]string{"detail_code": "G", "detail_name": "H"}, true, unknown, "major=A middle=A minor=C detail_code=G detail_name=H"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			columns := &cfg.Excel.Columns
+			columns.AutoDetect = tt.detect
+			columns.Minor = tt.columns["minor"]
+			columns.DetailCode = tt.columns["detail_code"]
+			columns.DetailName = tt.columns["detail_name"]
+
+			got, err := parser.SheetLayout(cfg, tt.rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Excel.Columns.DetailName = "5"
+	if _, err := parser.SheetLayout(cfg, nil); err == nil {
+		t.Error("want an error for an invalid column letter")
+	}
+}
@@ -2,99 +2,305 @@ package parser
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/llmcache"
 )
 
-type LLMClient struct {
-	client *openai.Client
-	config *config.Config
-}
+// PromptVersion 提示词版本，修改提示词时递增，使旧的缓存结果失效
+const PromptVersion = "3"
 
-func NewLLMClient(cfg *config.Config) *LLMClient {
-	if !cfg.AI.Enabled {
-		return nil
-	}
+// OpenAIMerger 通过 OpenAI 兼容接口合并名称，调用失败或结果无法解析时交给 fallback 处理
+type OpenAIMerger struct {
+	client     *openai.Client
+	config     *config.Config
+	normalizer *NameNormalizer
+	fallback   NameMerger
+	cache      *llmcache.Cache // 为 nil 时不缓存
+	limiter    *rateLimiter
+	// 当前使用的结构化输出级别(structuredModes 下标)，接口拒绝时降低，所有请求共享
+	format atomic.Int32
+}
 
+func NewOpenAIMerger(cfg *config.Config, normalizer *NameNormalizer, fallback NameMerger, cache *llmcache.Cache) *OpenAIMerger {
+	// 重试由 complete 统一处理，关闭 SDK 自带的重试
 	client := openai.NewClient(
 		option.WithAPIKey(cfg.GetAPIKey()),
 		option.WithBaseURL(cfg.AI.BaseURL),
+		option.WithMaxRetries(0),
 	)
 
-	return &LLMClient{
-		client: client,
-		config: cfg,
+	merger := &OpenAIMerger{
+		client:     client,
+		config:     cfg,
+		normalizer: normalizer,
+		fallback:   fallback,
+		cache:      cache,
+		limiter:    newRateLimiter(cfg.AI.RequestsPerSecond),
 	}
+	level, _ := structuredLevel(cfg.AI.StructuredOutput)
+	merger.format.Store(level)
+	return merger
 }
 
-func (l *LLMClient) MergeNamesWithLLM(namesText string) []string {
-	if l == nil || l.client == nil {
-		return l.fallbackProcessing(namesText)
+// MergeNames 请求大模型合并名称列中被拆开的职业名称。已知代码时要求名称数量与代码一致，
+// 数量不符或结果无法解析时把错误反馈给模型重新生成，最多 ai.reprompts 次
+func (l *OpenAIMerger) MergeNames(ctx context.Context, req MergeRequest, usage *LLMUsage) []string {
+	cleanedText := l.normalizer.Normalize(req.Text)
+	if cleanedText == "" {
+		fmt.Println("Warning: input is empty after normalization")
+		return nil
 	}
 
-	pureChineseText := KeepOnlyChinese(namesText)
-	if pureChineseText == "" {
-		fmt.Println("Warning: input is empty after filtering non-Chinese characters")
-		return nil
+	key := llmcache.Key{
+		Model:         l.config.AI.Model,
+		PromptVersion: PromptVersion,
+		Temperature:   l.config.AI.Temperature,
+		Input:         cleanedText,
+		Codes:         strings.Join(req.Codes, " "),
+	}
+	if l.cache != nil {
+		if entry, ok := l.cache.Get(key); ok {
+			usage.CacheHits++
+			return entry.Names
+		}
+		if l.config.AI.Cache.Offline {
+			usage.CacheMisses++
+			usage.Fallbacks++
+			return l.fallback.MergeNames(ctx, req, usage)
+		}
+		usage.CacheMisses++
 	}
 
-	prompt := fmt.Sprintf(`Please standardize these Chinese job titles into a clean JSON array:
-Rules:
-1. Merge fragmented names into complete job titles
-2. Split combined titles if they contain multiple independent jobs  
-3. Each entry should be a complete, standalone job title
-4. Keep only Chinese characters
-5. Output valid JSON format: ["职业名称1", "职业名称2", ...]
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("You are a job title processing expert, specializing in merging split Chinese job titles."),
+		openai.UserMessage(mergePrompt(cleanedText, req.Codes)),
+	}
 
-Input text:
-%s
+	for attempt := 0; ; attempt++ {
+		content, err := l.complete(ctx, messages, usage)
+		if err != nil {
+			fmt.Println("API call failed:", err)
+			usage.Failures++
+			usage.Fallbacks++
+			return l.fallback.MergeNames(ctx, req, usage)
+		}
 
-Expected output: JSON array of standardized Chinese job titles`, pureChineseText)
+		names, problem := l.decodeNames(content, req.Codes, usage)
+		if problem == "" {
+			if l.cache != nil {
+				if err := l.cache.Put(key, names); err != nil {
+					fmt.Println("Warning: failed to cache LLM result:", err)
+				}
+			}
+			return names
+		}
 
-	resp, err := l.client.Chat.Completions.New(
-		context.TODO(),
-		openai.ChatCompletionNewParams{
-			Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-				openai.SystemMessage("You are a job title processing expert, specializing in merging split Chinese job titles."),
-				openai.UserMessage(prompt),
-			}),
+		if attempt >= l.config.AI.Reprompts {
+			fmt.Printf("Warning: LLM result rejected after %d re-prompts (%s), using rules\n", attempt, problem)
+			usage.Fallbacks++
+			return l.fallback.MergeNames(ctx, req, usage)
+		}
+
+		usage.Reprompts++
+		messages = append(messages,
+			openai.AssistantMessage(content),
+			openai.UserMessage(repromptMessage(problem, req.Codes)),
+		)
+	}
+}
+
+// complete 发送一次对话请求：按 ai.requests_per_second 限速，单次请求超过 ai.timeout 时取消，
+// 遇到 429、5xx、超时或网络错误时按指数退避重试，最多 ai.max_retries 次；
+// 接口以 400 拒绝结构化输出(错误内容提到 response_format)时降低一级后立即重发，不计入重试次数
+func (l *OpenAIMerger) complete(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, usage *LLMUsage) (string, error) {
+	for attempt := 0; ; {
+		if err := l.limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		level := l.format.Load()
+		params := openai.ChatCompletionNewParams{
+			Messages:    openai.F(messages),
 			Model:       openai.F(l.config.AI.Model),
 			Temperature: openai.F(l.config.AI.Temperature),
-		},
-	)
-	if err != nil {
-		fmt.Println("API call failed:", err)
-		return l.fallbackProcessing(namesText)
+		}
+		if format := responseFormat(level); format != nil {
+			params.ResponseFormat = openai.F(format)
+		}
+
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if l.config.AI.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, l.config.AI.Timeout)
+		}
+		usage.Calls++
+		resp, err := l.client.Chat.Completions.New(callCtx, params)
+		cancel()
+
+		if err == nil {
+			usage.PromptTokens += resp.Usage.PromptTokens
+			usage.CompletionTokens += resp.Usage.CompletionTokens
+			if len(resp.Choices) == 0 {
+				return "", fmt.Errorf("response has no choices")
+			}
+			return resp.Choices[0].Message.Content, nil
+		}
+
+		var apiErr *openai.Error
+		if level > 0 && errors.As(err, &apiErr) && formatRejected(apiErr) {
+			// 其他请求可能已经降级，只有第一个降级的请求输出提示
+			if l.format.CompareAndSwap(level, level-1) {
+				fmt.Printf("Warning: LLM rejected response_format %s (%v), falling back to %s\n",
+					structuredModes[level], err, structuredModes[level-1])
+			}
+			usage.FormatFallbacks++
+			continue
+		}
+
+		if ctx.Err() != nil || !retryable(err) || attempt >= l.config.AI.MaxRetries {
+			return "", err
+		}
+
+		delay := retryDelay(err, l.config.AI.RetryBackoff<<attempt)
+		fmt.Printf("Warning: LLM request failed (%v), retrying in %s\n", err, delay)
+		usage.Retries++
+		attempt++
+		if err := sleep(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+}
+
+// responseFormat 返回结构化输出级别对应的 response_format，off 时返回 nil
+func responseFormat(level int32) openai.ChatCompletionNewParamsResponseFormatUnion {
+	switch structuredModes[level] {
+	case StructuredJSONSchema:
+		return openai.ResponseFormatJSONSchemaParam{
+			Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   openai.F("job_titles"),
+				Schema: openai.F[interface{}](namesSchema),
+				Strict: openai.F(true),
+			}),
+		}
+	case StructuredJSONObject:
+		return openai.ResponseFormatJSONObjectParam{
+			Type: openai.F(openai.ResponseFormatJSONObjectTypeJSONObject),
+		}
+	}
+	return nil
+}
+
+// formatRejected 判断 400 错误是否由 response_format 引起；SDK 只在错误体为平铺结构时填充 Message、Param，
+// 因此同时检查原始错误体。其他 400(如输入过长)降级也无济于事
+func formatRejected(apiErr *openai.Error) bool {
+	if apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	text := strings.ToLower(apiErr.Message + " " + apiErr.Param + " " + apiErr.JSON.RawJSON())
+	for _, keyword := range []string{"response_format", "json_schema", "json_object"} {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable 限流、服务端错误、超时和网络错误可以重试，其余错误(如 400、401)直接失败
+func retryable(err error) bool {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryDelay 服务端返回 Retry-After(秒)时以其为准，否则使用退避时间
+func retryDelay(err error, backoff time.Duration) time.Duration {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		if seconds, convErr := strconv.Atoi(apiErr.Response.Header.Get("Retry-After")); convErr == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
 	}
+	return backoff
+}
 
-	var result []string
-	content := resp.Choices[0].Message.Content
-	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		fmt.Println("JSON parsing failed:", err)
-		return l.fallbackProcessing(namesText)
+// decodeNames 解析并清洗模型返回的名称，结果不可用时返回问题描述
+func (l *OpenAIMerger) decodeNames(content string, codes []string, usage *LLMUsage) ([]string, string) {
+	result, rec, err := decodeNameList(content)
+	if err != nil {
+		usage.Recovery.Failed++
+		return nil, fmt.Sprintf(`the reply does not contain a JSON object like {"names": [...]}: %v`, err)
 	}
+	rec.count(&usage.Recovery)
 
-	var finalResults []string
+	var names []string
 	for _, name := range result {
-		cleaned := KeepOnlyChinese(name)
+		cleaned := l.normalizer.Normalize(name)
 		if cleaned != "" {
-			finalResults = append(finalResults, cleaned)
+			names = append(names, cleaned)
 		}
 	}
 
-	return finalResults
+	if len(codes) > 0 && len(names) != len(codes) {
+		return names, fmt.Sprintf("the reply has %d job titles but %d are required", len(names), len(codes))
+	}
+	return names, ""
+}
+
+func mergePrompt(text string, codes []string) string {
+	var count string
+	if len(codes) > 0 {
+		count = fmt.Sprintf(`6. The text belongs to exactly %d occupation codes: %s
+   Return exactly %d job titles, one per code, in the same order as the codes
+`, len(codes), strings.Join(codes, ", "), len(codes))
+	}
+
+	return fmt.Sprintf(`Please standardize these Chinese job titles into a clean JSON list:
+Rules:
+1. Merge fragmented names into complete job titles
+2. Split combined titles if they contain multiple independent jobs
+3. Each entry should be a complete, standalone job title
+4. Keep Chinese characters, the punctuation 、（） and any Latin letters or digits that belong to the title (e.g. CAD, 3D, 5G)
+5. Output only a valid JSON object: {"names": ["职业名称1", "职业名称2", ...]}
+%s
+Input text:
+%s
+
+Expected output: JSON object whose "names" array holds the standardized Chinese job titles`, count, text)
+}
+
+// repromptMessage 引用上一次回答的错误，要求模型修正
+func repromptMessage(problem string, codes []string) string {
+	if len(codes) == 0 {
+		return fmt.Sprintf("Your previous answer was rejected: %s. Reply with only the corrected JSON object.", problem)
+	}
+	return fmt.Sprintf("Your previous answer was rejected: %s. The codes are %s. "+
+		"Re-check which lines are fragments of the same title and reply with only a JSON object whose \"names\" array has exactly %d job titles.",
+		problem, strings.Join(codes, ", "), len(codes))
 }
 
-func (l *LLMClient) fallbackProcessing(namesText string) []string {
+// splitNameLines 规则处理：每个非空行视为一个名称
+func splitNameLines(namesText string, normalizer *NameNormalizer) []string {
 	lines := strings.Split(namesText, "\n")
 	var names []string
 	for _, line := range lines {
-		line = KeepOnlyChinese(strings.TrimSpace(line))
+		line = normalizer.Normalize(line)
 		if line != "" {
 			names = append(names, line)
 		}
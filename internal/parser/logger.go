@@ -1,14 +1,135 @@
 package parser
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/solisamicus/occstructor/internal/model"
 )
 
+// Mismatch 代码与名称数量不一致的一行，以 JSON Lines 格式写入不匹配日志
+type Mismatch struct {
+	Time     time.Time `json:"time"`
+	File     string    `json:"file"`
+	Sheet    string    `json:"sheet"`
+	Line     int       `json:"line"`
+	Rows     []int     `json:"rows,omitempty"` // 跨行拼接的记录涉及的全部行
+	Column   string    `json:"column"`
+	Codes    []string  `json:"codes"`
+	Names    []string  `json:"names"`
+	RawCodes string    `json:"raw_codes"`
+	RawNames string    `json:"raw_names"`
+	// Suggested 名称片段合并到与代码数量一致后的建议配对，无法给出时省略
+	Suggested []Pairing `json:"suggested,omitempty"`
+	// Fixed 人工修正后的配对，由 fixmismatch 命令读取入库
+	Fixed []Pairing `json:"fixed,omitempty"`
+}
+
+// Pairing 一个细类代码与名称的配对
+type Pairing struct {
+	Seq     string `json:"seq"`
+	Name    string `json:"name"`
+	Green   bool   `json:"green,omitempty"`
+	Digital bool   `json:"digital,omitempty"`
+}
+
+// titleEndings 完整职业名称常见的结尾，不以此结尾的名称多为被拆开的片段
+var titleEndings = []string{"员", "人", "师", "工", "手", "家", "官", "长", "理", "事"}
+
+// suggestPairing 名称多于代码时把相邻片段合并到与代码数量一致，再按顺序配对；
+// 名称少于代码时无法补出缺少的名称，不给出建议
+func suggestPairing(codes, names []string, green, digital []bool) []Pairing {
+	if len(codes) == 0 || len(names) < len(codes) {
+		return nil
+	}
+
+	names = slices.Clone(names)
+	green = slices.Clone(green)
+	digital = slices.Clone(digital)
+	for len(names) > len(codes) {
+		i := fragmentToMerge(names)
+		names[i] += names[i+1]
+		green[i] = green[i] || green[i+1]
+		digital[i] = digital[i] || digital[i+1]
+		names = slices.Delete(names, i+1, i+2)
+		green = slices.Delete(green, i+1, i+2)
+		digital = slices.Delete(digital, i+1, i+2)
+	}
+
+	pairs := make([]Pairing, len(codes))
+	for i, code := range codes {
+		pairs[i] = Pairing{Seq: code, Name: names[i], Green: green[i], Digital: digital[i]}
+	}
+	return pairs
+}
+
+// fragmentToMerge 选出与下一个名称合并的位置：优先考虑不以 titleEndings 结尾的片段，
+// 其中合并后最短的一对；没有这样的片段时在全部相邻名称中选合并后最短的一对
+func fragmentToMerge(names []string) int {
+	best, bestLen, bestFragment := 0, 0, false
+	for i := 0; i+1 < len(names); i++ {
+		fragment := !isCompleteTitle(names[i])
+		length := utf8.RuneCountInString(names[i]) + utf8.RuneCountInString(names[i+1])
+		if i == 0 || (fragment && !bestFragment) || (fragment == bestFragment && length < bestLen) {
+			best, bestLen, bestFragment = i, length, fragment
+		}
+	}
+	return best
+}
+
+func isCompleteTitle(name string) bool {
+	for _, ending := range titleEndings {
+		if strings.HasSuffix(name, ending) {
+			return true
+		}
+	}
+	return false
+}
+
+// Nodes 将配对转换为细类节点，来源指向不匹配的单元格
+func (m *Mismatch) Nodes(pairs []Pairing) []*model.OccupationNode {
+	nodes := make([]*model.OccupationNode, 0, len(pairs))
+	for _, pair := range pairs {
+		parentSeq := model.GetParentSeq(pair.Seq)
+		nodes = append(nodes, &model.OccupationNode{
+			Seq:       pair.Seq,
+			Name:      pair.Name,
+			Level:     4,
+			ParentSeq: &parentSeq,
+			Green:     pair.Green,
+			Digital:   pair.Digital,
+			Source: &model.Provenance{
+				File:    m.File,
+				Sheet:   m.Sheet,
+				Row:     m.Line,
+				Column:  m.Column,
+				RawText: m.RawNames,
+			},
+		})
+	}
+	return nodes
+}
+
+// MismatchSink 接收代码与名称数量不一致的行，实现需保证并发安全
+type MismatchSink interface {
+	LogMismatch(m *Mismatch)
+}
+
+type discardSink struct{}
+
+func (discardSink) LogMismatch(*Mismatch) {}
+
 type MismatchLogger struct {
+	mu      sync.Mutex
 	logFile *os.File
+	encoder *json.Encoder
 }
 
 func NewMismatchLogger() (*MismatchLogger, error) {
@@ -16,76 +137,69 @@ func NewMismatchLogger() (*MismatchLogger, error) {
 		return nil, err
 	}
 
-	filename := fmt.Sprintf("logs/mismatch_%s.log", time.Now().Format("20060102_150405"))
+	filename := fmt.Sprintf("logs/mismatch_%s.jsonl", time.Now().Format("20060102_150405"))
 	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MismatchLogger{logFile: file}, nil
-}
-
-func (l *MismatchLogger) LogMismatch(lineNum int, codes []string, names []string, rawCodes, rawNames string) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
 
-	logEntry := fmt.Sprintf(`
-========================================
-Time: %s
-Line: %d
-Code Count: %d
-Name Count: %d
-
-CODES:
-%s
-
-RAW CODES:
-%s
-
-NAMES:
-%s
+	return &MismatchLogger{logFile: file, encoder: encoder}, nil
+}
 
-RAW NAMES:
-%s
+// Path 返回日志文件路径
+func (l *MismatchLogger) Path() string {
+	return l.logFile.Name()
+}
 
-SQL INSERT TEMPLATE:
--- Line %d manual insert
-`, timestamp, lineNum, len(codes), len(names),
-		formatArray(codes), rawCodes,
-		formatArray(names), rawNames, lineNum)
+func (l *MismatchLogger) LogMismatch(m *Mismatch) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	for i, code := range codes {
-		if i < len(names) {
-			sqlTemplate := fmt.Sprintf("INSERT INTO occupations (seq, name, level, parent_seq) VALUES ('%s', '%s', 4, '%s');\n",
-				code, names[i], getParentSeq(code))
-			logEntry += sqlTemplate
-		} else {
-			sqlTemplate := fmt.Sprintf("-- Missing name for code: %s\n", code)
-			logEntry += sqlTemplate
-		}
+	if err := l.encoder.Encode(m); err != nil {
+		fmt.Printf("Failed to write mismatch log: %v\n", err)
 	}
-
-	logEntry += "\n"
-	l.logFile.WriteString(logEntry)
 }
 
-func (l *MismatchLogger) Close() {
+func (l *MismatchLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if l.logFile != nil {
-		l.logFile.Close()
+		return l.logFile.Close()
 	}
+	return nil
 }
 
-func formatArray(arr []string) string {
-	result := ""
-	for i, item := range arr {
-		result += fmt.Sprintf("[%d] %s\n", i, item)
+// ReadMismatchLog 读取 JSON Lines 格式的不匹配日志
+func ReadMismatchLog(path string) ([]*Mismatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
-	return result
-}
+	defer file.Close()
+
+	var mismatches []*Mismatch
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
 
-func getParentSeq(code string) string {
-	parts := strings.Split(code, "-")
-	if len(parts) >= 4 {
-		return strings.Join(parts[:3], "-")
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		m := &Mismatch{}
+		if err := json.Unmarshal(scanner.Bytes(), m); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		mismatches = append(mismatches, m)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
 	}
-	return ""
+
+	return mismatches, nil
 }
@@ -0,0 +1,151 @@
+package parser_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func TestSuggestPairing(t *testing.T) {
+	tests := []struct {
+		name    string
+		codes   []string
+		names   []string
+		green   []bool
+		want    string
+		wantNil bool
+	}{
+		{
+			name:  "fragment merged into next name",
+			codes: []string{"2-02-10-03", "2-02-10-04"},
+			names: []string{"计算机软件工程", "技术人员", "计算机网络工程技术人员"},
+			green: []bool{false, false, true},
+			want:  "2-02-10-03=计算机软件工程技术人员 2-02-10-04=计算机网络工程技术人员 L",
+		},
+		{
+			name:  "marker kept on merged name",
+			codes: []string{"4-01-01-01", "4-01-01-02"},
+			names: []string{"碳排放", "管理员", "车工"},
+			green: []bool{false, true, false},
+			want:  "4-01-01-01=碳排放管理员 L 4-01-01-02=车工",
+		},
+		{
+			name:  "shortest pair when all names look complete",
+			codes: []string{"6-18-01-01", "6-18-01-02"},
+			names: []string{"数控车床操作调整工", "车工", "铣工"},
+			green: []bool{false, false, false},
+			want:  "6-18-01-01=数控车床操作调整工 6-18-01-02=车工铣工",
+		},
+		{
+			name:  "several fragments",
+			codes: []string{"2-02-10-05"},
+			names: []string{"信息系统", "分析工程", "技术人员"},
+			green: []bool{false, false, false},
+			want:  "2-02-10-05=信息系统分析工程技术人员",
+		},
+		{
+			name:    "fewer names than codes",
+			codes:   []string{"2-02-10-03", "2-02-10-04"},
+			names:   []string{"计算机软件工程技术人员"},
+			green:   []bool{false},
+			wantNil: true,
+		},
+		{
+			name:    "no codes",
+			names:   []string{"车工"},
+			green:   []bool{false},
+			wantNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digital := make([]bool, len(tt.names))
+			before := fmt.Sprint(tt.names, tt.green)
+			pairs := parser.SuggestPairing(tt.codes, tt.names, tt.green, digital)
+			if tt.wantNil {
+				if pairs != nil {
+					t.Errorf("got %+v, want no suggestion", pairs)
+				}
+				return
+			}
+
+			var got []string
+			for _, pair := range pairs {
+				marker := ""
+				if pair.Green {
+					marker = " L"
+				}
+				got = append(got, pair.Seq+"="+pair.Name+marker)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %q, want %q", strings.Join(got, " "), tt.want)
+			}
+			if after := fmt.Sprint(tt.names, tt.green); after != before {
+				t.Errorf("input was modified: %s, want %s", after, before)
+			}
+		})
+	}
+}
+
+func TestReadMismatchLog(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	logger, err := parser.NewMismatchLogger()
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.LogMismatch(&parser.Mismatch{
+		Sheet:     "Sheet1",
+		Line:      86,
+		Rows:      []int{86, 87},
+		Codes:     []string{"2-03-01-00", "2-03-02-00"},
+		Names:     []string{"土壤肥料技术人员", "农业技术指导人员"},
+		RawNames:  "土壤肥料技术人员\n农业技术\n指导人员",
+		Suggested: []parser.Pairing{{Seq: "2-03-01-00", Name: "土壤肥料技术人员"}, {Seq: "2-03-02-00", Name: "农业技术指导人员", Green: true}},
+	})
+	logger.LogMismatch(&parser.Mismatch{Sheet: "Sheet1", Line: 90, Codes: []string{"2-03-03-00"}})
+	if err := logger.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 人工编辑时可能留下空行
+	data, err := os.ReadFile(logger.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(logger.Path(), append(data, '\n'), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := parser.ReadMismatchLog(logger.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatalf("got %d mismatches, want 2", len(mismatches))
+	}
+	m := mismatches[0]
+	if m.Line != 86 || fmt.Sprint(m.Rows) != "[86 87]" || m.RawNames != "土壤肥料技术人员\n农业技术\n指导人员" ||
+		len(m.Suggested) != 2 || !m.Suggested[1].Green || m.Fixed != nil {
+		t.Errorf("got %+v", m)
+	}
+	if mismatches[1].Suggested != nil {
+		t.Errorf("got suggestion %+v, want none", mismatches[1].Suggested)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.jsonl")
+	if err := os.WriteFile(bad, append(data, []byte("{not json\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ReadMismatchLog(bad); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("got %v, want an error for line 3", err)
+	}
+	if _, err := parser.ReadMismatchLog(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("want an error for a missing log")
+	}
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"strings"
+)
+
+// marker 细类名称后的职业标识，offset 为标识之前累计的名称字符数
+type marker struct {
+	offset  int
+	green   bool
+	digital bool
+}
+
+// splitMarker 去掉文本末尾的 L/S 标识，返回剩余文本及标识
+func splitMarker(text string) (string, bool, bool) {
+	text = strings.TrimSpace(text)
+	loc := MarkerRegex.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return text, false, false
+	}
+
+	tag := text[loc[2]:loc[3]]
+	return strings.TrimSpace(text[:loc[2]]), strings.ContainsAny(tag, "LＬ"), strings.ContainsAny(tag, "SＳ")
+}
+
+// stripMarkers 逐行去掉名称列中的 L/S 标识，保留换行
+func stripMarkers(namesText string) string {
+	lines := strings.Split(namesText, "\n")
+	for i, line := range lines {
+		lines[i], _, _ = splitMarker(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// extractMarkers 逐行扫描名称列，记录每个标识出现的位置
+func extractMarkers(namesText string, normalizer *NameNormalizer) []marker {
+	var markers []marker
+	offset := 0
+
+	for _, line := range strings.Split(namesText, "\n") {
+		rest, green, digital := splitMarker(line)
+		offset += normalizer.Len(normalizer.Normalize(rest))
+		if (green || digital) && offset > 0 {
+			markers = append(markers, marker{offset: offset, green: green, digital: digital})
+		}
+	}
+
+	return markers
+}
+
+// assignMarkers 按字符位置把标识分配给合并后的名称
+func assignMarkers(names []string, markers []marker, normalizer *NameNormalizer) (green, digital []bool) {
+	green = make([]bool, len(names))
+	digital = make([]bool, len(names))
+
+	ends := make([]int, len(names))
+	total := 0
+	for i, name := range names {
+		total += normalizer.Len(name)
+		ends[i] = total
+	}
+
+	for _, m := range markers {
+		for i, end := range ends {
+			if end >= m.offset {
+				green[i] = green[i] || m.green
+				digital[i] = digital[i] || m.digital
+				break
+			}
+		}
+	}
+
+	return green, digital
+}
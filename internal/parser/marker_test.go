@@ -0,0 +1,74 @@
+package parser_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func TestSplitMarker(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		green   bool
+		digital bool
+	}{
+		{"碳汇计量评估师 L", "碳汇计量评估师", true, false},
+		{"数据库运行管理员S", "数据库运行管理员", false, true},
+		{"智能制造工程技术人员 L/S", "智能制造工程技术人员", true, true},
+		{"智能制造工程技术人员 S / L", "智能制造工程技术人员", true, true},
+		{"  碳排放管理员 L  ", "碳排放管理员", true, false},
+		{"计算机软件工程技术人员 Ｓ", "计算机软件工程技术人员", false, true},
+		{"智能制造工程技术人员Ｌ／Ｓ", "智能制造工程技术人员", true, true},
+		{"ＳＱＬ／Ｓ", "ＳＱＬ／Ｓ", false, false},
+		{"输入/输出设备调试员", "输入/输出设备调试员", false, false},
+		{"TCP/IP", "TCP/IP", false, false},
+		{"SQL/S", "SQL/S", false, false},
+		{"CAD制图员", "CAD制图员", false, false},
+		{"S", "", false, true},
+		{"", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, green, digital := parser.SplitMarker(tt.in)
+			if got != tt.want || green != tt.green || digital != tt.digital {
+				t.Errorf("SplitMarker(%q) = %q, %v, %v, want %q, %v, %v", tt.in, got, green, digital, tt.want, tt.green, tt.digital)
+			}
+		})
+	}
+}
+
+func TestAssignMarkers(t *testing.T) {
+	n, err := parser.NewNameNormalizer(config.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		names   []string
+		green   string
+		digital string
+	}{
+		{"one per line", "碳汇计量评估师 L\n数据库运行管理员 S", []string{"碳汇计量评估师", "数据库运行管理员"}, "[true false]", "[false true]"},
+		{"both markers", "智能制造工程技术人员 L/S\n车工", []string{"智能制造工程技术人员", "车工"}, "[true false]", "[true false]"},
+		{"marker on continuation line", "计算机软件工程\n技术人员 S\n车工", []string{"计算机软件工程技术人员", "车工"}, "[false false]", "[true false]"},
+		{"marker on last fragment", "车工\n碳排放\n管理员 L", []string{"车工", "碳排放管理员"}, "[false true]", "[false false]"},
+		{"marker on its own line", "碳汇计量评估师\nL\n车工", []string{"碳汇计量评估师", "车工"}, "[true false]", "[false false]"},
+		{"slash inside name", "输入/输出设备调试员\n碳排放管理员 L", []string{"输入/输出设备调试员", "碳排放管理员"}, "[false true]", "[false false]"},
+		{"no markers", "车工\n铣工", []string{"车工", "铣工"}, "[false false]", "[false false]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			green, digital := parser.AssignMarkers(tt.names, tt.text, n)
+			if fmt.Sprint(green) != tt.green || fmt.Sprint(digital) != tt.digital {
+				t.Errorf("got green %v digital %v, want %s %s", green, digital, tt.green, tt.digital)
+			}
+		})
+	}
+}
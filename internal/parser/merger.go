@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/llmcache"
+)
+
+// 名称合并方式，对应配置 ai.provider
+const (
+	ProviderOpenAI = "openai" // OpenAI 兼容接口，如 DashScope
+	ProviderRules  = "rules"  // 规则处理，不调用大模型
+	ProviderRecord = "record" // 调用 OpenAI 兼容接口并把结果录制到 fixtures 文件
+	ProviderReplay = "replay" // 从 fixtures 文件回放，离线运行
+)
+
+// MergeRequest 一个名称单元格的合并请求
+type MergeRequest struct {
+	Text  string   // 去掉职业标识后的名称单元格文本
+	Codes []string // 同一记录的细类代码，名称数量应与之一致；为空时不约束数量
+}
+
+// NameMerger 合并名称列中被拆开的职业名称，实现需可在多个 goroutine 中同时使用
+type NameMerger interface {
+	// MergeNames 返回清洗后的名称列表，调用次数与 token 用量累计到 usage
+	MergeNames(ctx context.Context, req MergeRequest, usage *LLMUsage) []string
+}
+
+// NewNameMerger 按配置创建名称合并器，未启用 AI 时使用规则处理
+func NewNameMerger(cfg *config.Config, normalizer *NameNormalizer) (NameMerger, error) {
+	rules := NewRuleMerger(normalizer)
+	if !cfg.AI.Enabled {
+		return rules, nil
+	}
+
+	if _, ok := structuredLevel(cfg.AI.StructuredOutput); !ok {
+		return nil, fmt.Errorf("unknown ai structured_output %q", cfg.AI.StructuredOutput)
+	}
+
+	// 离线模式依赖缓存，未启用缓存时会照常调用接口
+	if cfg.AI.Cache.Offline && !cfg.AI.Cache.Enabled {
+		return nil, fmt.Errorf("ai.cache.offline requires ai.cache.enabled")
+	}
+
+	var cache *llmcache.Cache
+	if cfg.AI.Cache.Enabled {
+		var err error
+		if cache, err = llmcache.Open(cfg.AI.Cache.Dir); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.AI.Provider {
+	case "", ProviderOpenAI:
+		return NewOpenAIMerger(cfg, normalizer, rules, cache), nil
+	case ProviderRules:
+		return rules, nil
+	case ProviderRecord:
+		return NewRecordingMerger(cfg.AI.Fixtures, NewOpenAIMerger(cfg, normalizer, rules, cache))
+	case ProviderReplay:
+		return NewReplayMerger(cfg.AI.Fixtures, rules)
+	default:
+		return nil, fmt.Errorf("unknown ai provider %q", cfg.AI.Provider)
+	}
+}
+
+// RuleMerger 规则处理：每个非空行视为一个名称
+type RuleMerger struct {
+	normalizer *NameNormalizer
+}
+
+func NewRuleMerger(normalizer *NameNormalizer) *RuleMerger {
+	return &RuleMerger{normalizer: normalizer}
+}
+
+func (m *RuleMerger) MergeNames(_ context.Context, req MergeRequest, _ *LLMUsage) []string {
+	return splitNameLines(req.Text, m.normalizer)
+}
@@ -0,0 +1,518 @@
+package parser_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"-"`
+}
+
+// UnmarshalJSON 内容可能是字符串，也可能是 [{"type": "text", "text": ...}] 形式的分段
+func (m *chatMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Role = raw.Role
+
+	if err := json.Unmarshal(raw.Content, &m.Content); err == nil {
+		return nil
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw.Content, &parts); err != nil {
+		return err
+	}
+	for _, part := range parts {
+		m.Content += part.Text
+	}
+	return nil
+}
+
+// fakeChatServer 本地模拟 OpenAI 兼容的 chat/completions 接口，返回固定的合并结果
+func fakeChatServer(t *testing.T, names []string) (*httptest.Server, *atomic.Int32) {
+	content, _ := json.Marshal(names)
+	return scriptedChatServer(t, func([]chatMessage) string { return string(content) })
+}
+
+// scriptedChatServer 由 reply 根据对话内容生成回答
+func scriptedChatServer(t *testing.T, reply func([]chatMessage) string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+
+		var req struct {
+			Model    string        `json:"model"`
+			Messages []chatMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "fake-model" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-test",
+			"object":  "chat.completion",
+			"created": 0,
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]interface{}{"role": "assistant", "content": reply(req.Messages)},
+			}},
+			"usage": map[string]interface{}{"prompt_tokens": 30, "completion_tokens": 10, "total_tokens": 40},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func splitNamesWorkbook(t *testing.T) string {
+	return writeWorkbook(t, [][]string{
+		{"第二大类    2 (GBM20000) 专业技术人员"},
+		{
+			"2-02 (GBM 20200) 工程技术人员", "",
+			"2-02-10 (GBM 20210) 信息和通信工程技术人员", "",
+			"2-02-10-03\n2-02-10-04",
+			"计算机软件工程\n技术人员\n计算机网络工程技术人员",
+		},
+	})
+}
+
+func aiConfig(t *testing.T, baseURL string) *config.Config {
+	t.Setenv("OCC_TEST_API_KEY", "test-key")
+
+	cfg := config.DefaultConfig()
+	cfg.AI.Enabled = true
+	cfg.AI.BaseURL = baseURL + "/"
+	cfg.AI.APIKeyEnv = "OCC_TEST_API_KEY"
+	cfg.AI.Model = "fake-model"
+	return cfg
+}
+
+func detailNames(t *testing.T, cfg *config.Config, path string) ([]string, *parser.ParseReport) {
+	t.Helper()
+
+	p, err := parser.NewExcelParser(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, report, err := p.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, node := range result.SubMinors {
+		names = append(names, node.Seq+" "+node.Name)
+	}
+	return names, report
+}
+
+func TestOpenAIMergerParse(t *testing.T) {
+	server, calls := fakeChatServer(t, []string{"计算机软件工程技术人员", "计算机网络工程技术人员"})
+	path := splitNamesWorkbook(t)
+
+	names, report := detailNames(t, aiConfig(t, server.URL), path)
+	want := "2-02-10-03 计算机软件工程技术人员|2-02-10-04 计算机网络工程技术人员"
+	if strings.Join(names, "|") != want {
+		t.Errorf("got %q, want %q", names, want)
+	}
+	if calls.Load() != 1 || report.LLM.Calls != 1 || report.LLM.PromptTokens != 30 || report.LLM.CompletionTokens != 10 {
+		t.Errorf("got %d server calls, usage %+v", calls.Load(), report.LLM)
+	}
+	if len(report.Mismatches) != 0 {
+		t.Errorf("got mismatches %+v", report.Mismatches)
+	}
+
+	// 规则处理把每行视为一个名称，数量不匹配
+	cfg := aiConfig(t, server.URL)
+	cfg.AI.Provider = parser.ProviderRules
+	if names, report := detailNames(t, cfg, path); len(names) != 0 || len(report.Mismatches) != 1 {
+		t.Errorf("got %q and %d mismatches with rules, want a mismatch", names, len(report.Mismatches))
+	}
+}
+
+func TestRecordReplayMerger(t *testing.T) {
+	server, calls := fakeChatServer(t, []string{"计算机软件工程技术人员", "计算机网络工程技术人员"})
+	path := splitNamesWorkbook(t)
+	fixtures := filepath.Join(t.TempDir(), "fixtures", "llm.jsonl")
+
+	// 回答无法解析时回退到规则处理，规则结果不录制
+	bad, _ := scriptedChatServer(t, func([]chatMessage) string { return "no names here" })
+	cfg := aiConfig(t, bad.URL)
+	cfg.AI.Provider = parser.ProviderRecord
+	cfg.AI.Fixtures = fixtures
+	cfg.AI.Reprompts = 0
+	if _, report := detailNames(t, cfg, path); report.LLM.Fallbacks != 1 {
+		t.Errorf("got usage %+v with an unparsable reply", report.LLM)
+	}
+	if loaded, err := parser.LoadFixtures(fixtures); err == nil && len(loaded) != 0 {
+		t.Errorf("recorded rule fallback %v", loaded)
+	}
+
+	cfg = aiConfig(t, server.URL)
+	cfg.AI.Provider = parser.ProviderRecord
+	cfg.AI.Fixtures = fixtures
+	recorded, _ := detailNames(t, cfg, path)
+	detailNames(t, cfg, path)
+	if calls.Load() != 2 {
+		t.Fatalf("got %d server calls while recording, want 2", calls.Load())
+	}
+
+	loaded, err := parser.LoadFixtures(fixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 {
+		t.Errorf("got %d fixtures, want 1: %v", len(loaded), loaded)
+	}
+
+	server.Close()
+	cfg.AI.Provider = parser.ProviderReplay
+	replayed, report := detailNames(t, cfg, path)
+	if strings.Join(replayed, "|") != strings.Join(recorded, "|") || len(replayed) != 2 {
+		t.Errorf("replayed %q, recorded %q", replayed, recorded)
+	}
+	if report.LLM.Calls != 0 || report.LLM.Fallbacks != 0 {
+		t.Errorf("got usage %+v while replaying", report.LLM)
+	}
+
+	cfg.AI.Provider = "unknown"
+	if _, err := parser.NewExcelParser(cfg, nil); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestOpenAIMergerCache(t *testing.T) {
+	server, calls := fakeChatServer(t, []string{"计算机软件工程技术人员", "计算机网络工程技术人员"})
+	path := splitNamesWorkbook(t)
+
+	cfg := aiConfig(t, server.URL)
+	cfg.AI.Cache.Enabled = true
+	cfg.AI.Cache.Dir = filepath.Join(t.TempDir(), "llm")
+
+	first, report := detailNames(t, cfg, path)
+	if report.LLM.CacheMisses != 1 || report.LLM.CacheHits != 0 {
+		t.Errorf("first run usage %+v", report.LLM)
+	}
+
+	// 离线模式下命中缓存，不访问接口
+	server.Close()
+	cfg.AI.Cache.Offline = true
+	second, report := detailNames(t, cfg, path)
+	if strings.Join(first, "|") != strings.Join(second, "|") || len(second) != 2 {
+		t.Errorf("cached run got %q, want %q", second, first)
+	}
+	if calls.Load() != 1 || report.LLM.Calls != 0 || report.LLM.CacheHits != 1 {
+		t.Errorf("got %d server calls, usage %+v", calls.Load(), report.LLM)
+	}
+
+	// 温度变化后不命中，离线时回退到规则处理
+	cfg.AI.Temperature = 0.5
+	if _, report := detailNames(t, cfg, path); report.LLM.CacheMisses != 1 || report.LLM.Fallbacks != 1 {
+		t.Errorf("got usage %+v after changing temperature", report.LLM)
+	}
+
+	// 未启用缓存时离线模式不生效，直接报错
+	cfg.AI.Cache.Enabled = false
+	if _, err := parser.NewExcelParser(cfg, nil); err == nil {
+		t.Error("expected an error for offline without cache")
+	}
+}
+
+func TestOpenAIMergerReprompt(t *testing.T) {
+	var conversations [][]chatMessage
+	server, _ := scriptedChatServer(t, func(messages []chatMessage) string {
+		conversations = append(conversations, messages)
+		if len(messages) == 2 {
+			return `["计算机软件工程", "技术人员", "计算机网络工程技术人员"]`
+		}
+		return `["计算机软件工程技术人员", "计算机网络工程技术人员"]`
+	})
+	path := splitNamesWorkbook(t)
+
+	names, report := detailNames(t, aiConfig(t, server.URL), path)
+	if len(names) != 2 || len(report.Mismatches) != 0 {
+		t.Errorf("got %q and mismatches %+v", names, report.Mismatches)
+	}
+	if report.LLM.Calls != 2 || report.LLM.Reprompts != 1 || report.LLM.Fallbacks != 0 {
+		t.Errorf("got usage %+v", report.LLM)
+	}
+
+	if len(conversations) != 2 {
+		t.Fatalf("got %d requests, want 2", len(conversations))
+	}
+	if prompt := conversations[0][1].Content; !strings.Contains(prompt, "exactly 2 occupation codes: 2-02-10-03, 2-02-10-04") {
+		t.Errorf("prompt does not state the expected count: %s", prompt)
+	}
+	retry := conversations[1]
+	if len(retry) != 4 || retry[2].Role != "assistant" || !strings.Contains(retry[3].Content, "has 3 job titles but 2 are required") {
+		t.Errorf("re-prompt does not quote the error: %+v", retry)
+	}
+
+	// 一直数量不符时在 reprompts 次后放弃，回退到规则处理
+	always, calls := scriptedChatServer(t, func([]chatMessage) string { return `["计算机软件工程技术人员"]` })
+	cfg := aiConfig(t, always.URL)
+	cfg.AI.Reprompts = 1
+	if _, report := detailNames(t, cfg, path); calls.Load() != 2 || report.LLM.Reprompts != 1 || report.LLM.Fallbacks != 1 {
+		t.Errorf("got %d calls, usage %+v", calls.Load(), report.LLM)
+	}
+}
+
+func TestOpenAIMergerRetry(t *testing.T) {
+	var calls atomic.Int32
+	ok, _ := fakeChatServer(t, []string{"计算机软件工程技术人员", "计算机网络工程技术人员"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"error": {"message": "rate limited"}}`, http.StatusTooManyRequests)
+		case 2:
+			http.Error(w, `{"error": {"message": "unavailable"}}`, http.StatusServiceUnavailable)
+		case 3:
+			time.Sleep(200 * time.Millisecond)
+			fallthrough
+		default:
+			r.URL.Path = "/chat/completions"
+			ok.Config.Handler.ServeHTTP(w, r)
+		}
+	}))
+	defer server.Close()
+	path := splitNamesWorkbook(t)
+
+	cfg := aiConfig(t, server.URL)
+	cfg.AI.Timeout = 50 * time.Millisecond
+	cfg.AI.RetryBackoff = time.Millisecond
+	names, report := detailNames(t, cfg, path)
+	if len(names) != 2 || report.LLM.Calls != 4 || report.LLM.Retries != 3 || report.LLM.Failures != 0 {
+		t.Errorf("got %q, usage %+v", names, report.LLM)
+	}
+
+	// 客户端错误不重试
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": {"message": "bad key"}}`, http.StatusUnauthorized)
+	}))
+	defer bad.Close()
+	cfg = aiConfig(t, bad.URL)
+	if _, report := detailNames(t, cfg, path); report.LLM.Calls != 1 || report.LLM.Failures != 1 || report.LLM.Fallbacks != 1 {
+		t.Errorf("got usage %+v for a 401", report.LLM)
+	}
+}
+
+func TestMergeNamesConcurrentOrder(t *testing.T) {
+	codesRegex := regexp.MustCompile(`occupation codes: ([0-9, -]+)`)
+	var inFlight, maxInFlight atomic.Int32
+	server, _ := scriptedChatServer(t, func(messages []chatMessage) string {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+
+		// 先到的请求等得更久，打乱完成顺序
+		codes := strings.Split(codesRegex.FindStringSubmatch(messages[1].Content)[1], ", ")
+		last := codes[0][len(codes[0])-2:]
+		seg, _ := strconv.Atoi(last)
+		time.Sleep(time.Duration(10-seg) * 10 * time.Millisecond)
+
+		content, _ := json.Marshal([]string{"测试职业" + last})
+		return string(content)
+	})
+
+	rows := [][]string{{"第二大类    2 (GBM20000) 专业技术人员"}}
+	var want []string
+	for i := 1; i <= 8; i++ {
+		code := fmt.Sprintf("2-02-10-%02d", i)
+		rows = append(rows, []string{"", "", "", "", code, fmt.Sprintf("测试\n职业%02d", i)})
+		want = append(want, fmt.Sprintf("%s 测试职业%02d", code, i))
+	}
+	path := writeWorkbook(t, rows)
+
+	cfg := aiConfig(t, server.URL)
+	cfg.AI.Concurrency = 4
+	names, report := detailNames(t, cfg, path)
+	if strings.Join(names, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", names, want)
+	}
+	if report.LLM.Calls != 8 || report.LLM.PromptTokens != 8*30 {
+		t.Errorf("got usage %+v", report.LLM)
+	}
+	if max := maxInFlight.Load(); max < 2 || max > 4 {
+		t.Errorf("got %d concurrent requests, want 2..4", max)
+	}
+}
+
+func TestOpenAIMergerReplyRecovery(t *testing.T) {
+	path := splitNamesWorkbook(t)
+	tests := []struct {
+		name  string
+		reply string
+		want  parser.RecoveryStats
+	}{
+		{"array", `["计算机软件工程技术人员", "计算机网络工程技术人员"]`, parser.RecoveryStats{Direct: 1}},
+		{"object", `{"names": ["计算机软件工程技术人员", "计算机网络工程技术人员"]}`, parser.RecoveryStats{Direct: 1}},
+		{"other field", `{"titles": ["计算机软件工程技术人员", "计算机网络工程技术人员"]}`, parser.RecoveryStats{Object: 1}},
+		{"fenced", "```json\n[\"计算机软件工程技术人员\", \"计算机网络工程技术人员\"]\n```", parser.RecoveryStats{Fenced: 1}},
+		{"fenced object", "```\n{\"job_titles\": [\"计算机软件工程技术人员\", \"计算机网络工程技术人员\"]}\n```", parser.RecoveryStats{Fenced: 1, Object: 1}},
+		{"prose", "合并结果如下 [2 条]：\n[\"计算机软件工程技术人员\", \"计算机网络工程技术人员\"]\n请核对。", parser.RecoveryStats{Prose: 1}},
+		{"prose object", `Here you go: {"count": 2, "result": ["计算机软件工程技术人员", "计算机网络工程技术人员"]} Done.`, parser.RecoveryStats{Prose: 1, Object: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := scriptedChatServer(t, func([]chatMessage) string { return tt.reply })
+			names, report := detailNames(t, aiConfig(t, server.URL), path)
+			if len(names) != 2 || report.LLM.Reprompts != 0 || report.LLM.Fallbacks != 0 {
+				t.Errorf("got %q, usage %+v", names, report.LLM)
+			}
+			if report.LLM.Recovery != tt.want {
+				t.Errorf("got recovery %+v, want %+v", report.LLM.Recovery, tt.want)
+			}
+		})
+	}
+
+	// 找不到 JSON 时重新提问，仍然失败则交给规则处理
+	server, calls := scriptedChatServer(t, func([]chatMessage) string { return "抱歉，无法处理。" })
+	cfg := aiConfig(t, server.URL)
+	cfg.AI.Reprompts = 1
+	_, report := detailNames(t, cfg, path)
+	if calls.Load() != 2 || report.LLM.Recovery.Failed != 2 || report.LLM.Fallbacks != 1 {
+		t.Errorf("got %d calls, usage %+v", calls.Load(), report.LLM)
+	}
+}
+
+func TestOpenAIMergerStructuredOutput(t *testing.T) {
+	var formats []string
+	var mu sync.Mutex
+	ok, _ := scriptedChatServer(t, func([]chatMessage) string {
+		return `{"names": ["计算机软件工程技术人员", "计算机网络工程技术人员"]}`
+	})
+	// 模拟只支持 json_object 的接口
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ResponseFormat *struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		json.Unmarshal(body, &req)
+		format := "none"
+		if req.ResponseFormat != nil {
+			format = req.ResponseFormat.Type
+		}
+		mu.Lock()
+		formats = append(formats, format)
+		mu.Unlock()
+
+		if format == "json_schema" {
+			http.Error(w, `{"error": {"message": "response_format json_schema is not supported"}}`, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		ok.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	path := splitNamesWorkbook(t)
+
+	cfg := aiConfig(t, server.URL)
+	names, report := detailNames(t, cfg, path)
+	if len(names) != 2 || report.LLM.FormatFallbacks != 1 || report.LLM.Failures != 0 || report.LLM.Recovery.Direct != 1 {
+		t.Errorf("got %q, usage %+v", names, report.LLM)
+	}
+	if strings.Join(formats, ",") != "json_schema,json_object" {
+		t.Errorf("got response formats %q", formats)
+	}
+
+	formats = nil
+	cfg = aiConfig(t, server.URL)
+	cfg.AI.StructuredOutput = parser.StructuredOff
+	if names, report := detailNames(t, cfg, path); len(names) != 2 || report.LLM.FormatFallbacks != 0 || strings.Join(formats, ",") != "none" {
+		t.Errorf("got %q with formats %q, usage %+v", names, formats, report.LLM)
+	}
+
+	cfg.AI.StructuredOutput = "xml"
+	if _, err := parser.NewExcelParser(cfg, nil); err == nil {
+		t.Error("want an error for an unknown structured_output")
+	}
+}
+
+func TestOpenAIMergerPlainBadRequest(t *testing.T) {
+	var formats []string
+	ok, _ := scriptedChatServer(t, func([]chatMessage) string {
+		return `{"names": ["计算机软件工程技术人员", "计算机网络工程技术人员"]}`
+	})
+	// 第一次请求以与结构化输出无关的 400 拒绝
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ResponseFormat struct {
+				Type string `json:"type"`
+			} `json:"response_format"`
+		}
+		json.Unmarshal(body, &req)
+		formats = append(formats, req.ResponseFormat.Type)
+
+		if len(formats) == 1 {
+			http.Error(w, `{"error": {"message": "Range of input length should be [1, 30720]", "param": "messages"}}`, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		ok.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	path := splitNamesWorkbook(t)
+
+	p, err := parser.NewExcelParser(aiConfig(t, server.URL), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, report, err := p.ParseFile(path); err != nil {
+		t.Fatal(err)
+	} else if report.LLM.Failures != 1 || report.LLM.FormatFallbacks != 0 {
+		t.Errorf("got usage %+v after a plain 400", report.LLM)
+	}
+
+	// 之后的请求仍使用 json_schema
+	if _, report, err := p.ParseFile(path); err != nil {
+		t.Fatal(err)
+	} else if report.LLM.Failures != 0 || report.LLM.FormatFallbacks != 0 {
+		t.Errorf("got usage %+v on the second run", report.LLM)
+	}
+	if strings.Join(formats, ",") != "json_schema,json_schema" {
+		t.Errorf("got response formats %q", formats)
+	}
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/solisamicus/occstructor/internal/config"
+)
+
+// NameNormalizer 职业名称清洗流程：
+// 去除 OCR 噪声 → 全角/半角折叠 → 保留汉字、白名单标点及拉丁字母/数字 → 去除首尾分隔符
+type NameNormalizer struct {
+	foldWidth   bool
+	keepLatin   bool
+	keepDigits  bool
+	punctuation map[rune]bool
+	junk        []*regexp.Regexp
+}
+
+func NewNameNormalizer(cfg *config.Config) (*NameNormalizer, error) {
+	opts := cfg.Normalize
+
+	n := &NameNormalizer{
+		foldWidth:   opts.FoldWidth,
+		keepLatin:   opts.KeepLatin,
+		keepDigits:  opts.KeepDigits,
+		punctuation: make(map[rune]bool),
+	}
+
+	for _, r := range opts.Punctuation {
+		if n.foldWidth {
+			r = foldWidth(r)
+		}
+		n.punctuation[r] = true
+	}
+
+	for _, pattern := range opts.JunkPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid junk pattern %q: %w", pattern, err)
+		}
+		n.junk = append(n.junk, re)
+	}
+
+	return n, nil
+}
+
+func (n *NameNormalizer) Normalize(text string) string {
+	for _, re := range n.junk {
+		text = re.ReplaceAllString(text, "")
+	}
+
+	var b strings.Builder
+	var prev rune
+	pendingSpace := false
+
+	for _, r := range text {
+		if n.foldWidth {
+			r = foldWidth(r)
+		}
+
+		if unicode.IsSpace(r) {
+			pendingSpace = true
+			continue
+		}
+		if !n.keep(r) {
+			continue
+		}
+
+		// OCR 在汉字之间插入的空格全部去掉，只保留拉丁字母/数字之间的单个空格
+		if pendingSpace && isLatinOrDigit(prev) && isLatinOrDigit(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+		prev = r
+		pendingSpace = false
+	}
+
+	return strings.TrimFunc(b.String(), isSeparator)
+}
+
+// Len 名称长度，不计空格，用于在原文与合并后的名称之间对齐位置
+func (n *NameNormalizer) Len(name string) int {
+	return utf8.RuneCountInString(name) - strings.Count(name, " ")
+}
+
+func (n *NameNormalizer) keep(r rune) bool {
+	switch {
+	case unicode.Is(unicode.Han, r):
+		return true
+	case r < utf8.RuneSelf && unicode.IsLetter(r):
+		return n.keepLatin
+	case r >= '0' && r <= '9':
+		return n.keepDigits
+	default:
+		return n.punctuation[r]
+	}
+}
+
+// foldWidth 全角字母数字转为半角，半角括号转为全角，全角空格转为半角空格
+func foldWidth(r rune) rune {
+	switch {
+	case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+		return r - 0xFEE0
+	case r == '(':
+		return '（'
+	case r == ')':
+		return '）'
+	case r == ',':
+		return '，'
+	case r == '　':
+		return ' '
+	default:
+		return r
+	}
+}
+
+func isLatinOrDigit(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isSeparator(r rune) bool {
+	return strings.ContainsRune("、，；：·", r)
+}
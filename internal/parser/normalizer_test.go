@@ -0,0 +1,68 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/solisamicus/occstructor/internal/parser"
+)
+
+func TestNameNormalizer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Normalize.JunkPatterns = []string{`[|_]`}
+
+	n, err := parser.NewNameNormalizer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"enumeration comma", "党的机关、  国家机关、  群众团体和社会组织、  企事业单位负责人", "党的机关、国家机关、群众团体和社会组织、企事业单位负责人"},
+		{"ocr spaces", "人 民 检 察 院 负\n责人", "人民检察院负责人"},
+		{"line break", "中国\n共产 党 机 关 和 基 层 组 织 负\n责人", "中国共产党机关和基层组织负责人"},
+		{"comma in title", "文学、  艺术学研\n究人员", "文学、艺术学研究人员"},
+		{"full-width parentheses", "其他农、林、牧、渔业生产及辅助人员（含 渔 业）", "其他农、林、牧、渔业生产及辅助人员（含渔业）"},
+		{"half-width parentheses", "其他办事人员(含警察)", "其他办事人员（含警察）"},
+		{"digits", "3D打印设备操作员", "3D打印设备操作员"},
+		{"latin", "CAD制图员", "CAD制图员"},
+		{"full-width latin and digits", "５Ｇ网络工程技术人员", "5G网络工程技术人员"},
+		{"space between latin runs", "Web 前端开发工程技术人员", "Web前端开发工程技术人员"},
+		{"space inside latin", "ERP 3D 建模员", "ERP 3D建模员"},
+		{"junk removed", "|信息安全工程技_术人员", "信息安全工程技术人员"},
+		{"leading separator", "、企业经理", "企业经理"},
+		{"symbols dropped", "电子竞技员*#", "电子竞技员"},
+		{"empty", "  \n ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNameNormalizerChineseOnly(t *testing.T) {
+	n, err := parser.NewNameNormalizer(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := n.Normalize("文学、艺术学研究人员(3D)"), "文学艺术学研究人员"; got != want {
+		t.Errorf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestNameNormalizerInvalidPattern(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Normalize.JunkPatterns = []string{`(`}
+
+	if _, err := parser.NewNameNormalizer(cfg); err == nil {
+		t.Error("expected error for invalid junk pattern")
+	}
+}
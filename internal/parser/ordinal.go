@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 中文数字，"-" 与 "—" 是 OCR 对 "一" 的常见误识别
+var chineseDigits = map[rune]int{
+	'〇': 0, '零': 0,
+	'一': 1, '-': 1, '—': 1,
+	'二': 2, '两': 2,
+	'三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+// OrdinalMismatch 大类标题中 "第X大类" 的序数与大类编号不一致
+type OrdinalMismatch struct {
+	File    string `json:"file"`
+	Sheet   string `json:"sheet"`
+	Row     int    `json:"row"`
+	Ordinal string `json:"ordinal"` // 标题中的序数原文
+	Seq     string `json:"seq"`     // 标题中的编号，缺失时为空
+	GBM     string `json:"gbm"`
+	Used    string `json:"used"` // 最终采用的编号
+}
+
+// ParseOrdinal 将 "第X大类" 中的序数转换为整数，支持阿拉伯数字和 一..九十九 的中文数字
+func ParseOrdinal(text string) (int, bool) {
+	text = strings.Join(strings.Fields(norm.NFKC.String(text)), "")
+	if text == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return n, n > 0
+	}
+
+	runes := []rune(text)
+	tens, i := 0, 0
+	if len(runes) > 1 && runes[1] == '十' {
+		d, ok := chineseDigits[runes[0]]
+		if !ok || d == 0 {
+			return 0, false
+		}
+		tens, i = d, 2
+	} else if runes[0] == '十' {
+		tens, i = 1, 1
+	}
+
+	switch rest := runes[i:]; len(rest) {
+	case 0:
+		return tens * 10, tens > 0
+	case 1:
+		d, ok := chineseDigits[rest[0]]
+		if !ok || (tens == 0 && d == 0) {
+			return 0, false
+		}
+		return tens*10 + d, true
+	}
+	return 0, false
+}
+
+// resolveMajorSeq 用序数、标题编号和 GBM 首位交叉核对大类编号。
+// 三者中两者一致时采用一致的值；序数无法识别或无法裁决时保留标题编号，编号缺失时采用序数，
+// 序数和编号都不可用时采用 GBM 首位，仍无法确定时返回空串
+func (p *sheetParser) resolveMajorSeq(row sheetRow, ordinal, seq, gbm string) string {
+	n, ok := ParseOrdinal(ordinal)
+	switch {
+	case !ok:
+		used := seq
+		if used == "" {
+			used = gbmMajorDigit(gbm)
+		}
+		p.recordOrdinalMismatch(row, ordinal, seq, gbm, used)
+		return used
+	case seq == "":
+		return strconv.Itoa(n)
+	}
+
+	want := strconv.Itoa(n)
+	if seq == want {
+		return seq
+	}
+
+	used := seq
+	if strings.HasPrefix(gbm, want) && !strings.HasPrefix(gbm, seq) {
+		used = want
+	}
+	p.recordOrdinalMismatch(row, ordinal, seq, gbm, used)
+	return used
+}
+
+// gbmMajorDigit 返回 GBM 编码的首位(1-9)，不是数字时返回空串
+func gbmMajorDigit(gbm string) string {
+	gbm = strings.TrimSpace(gbm)
+	if gbm == "" || gbm[0] < '1' || gbm[0] > '9' {
+		return ""
+	}
+	return gbm[:1]
+}
+
+func (p *sheetParser) recordOrdinalMismatch(row sheetRow, ordinal, seq, gbm, used string) {
+	fmt.Printf("Warning: line %d major ordinal 第%s大类 does not match seq %q, using %q\n", row.Num, ordinal, seq, used)
+	p.report.OrdinalMismatches = append(p.report.OrdinalMismatches, OrdinalMismatch{
+		File:    p.file,
+		Sheet:   p.sheet,
+		Row:     row.Num,
+		Ordinal: ordinal,
+		Seq:     seq,
+		GBM:     gbm,
+		Used:    used,
+	})
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter 按固定间隔放行请求，可在多个 goroutine 中共用；rps <= 0 时不限速
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	if rps <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// Wait 阻塞到下一个可用的时间点，ctx 取消时返回错误
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	if r.interval == 0 {
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	at := r.next
+	if at.Before(now) {
+		at = now
+	}
+	r.next = at.Add(r.interval)
+	r.mu.Unlock()
+
+	return sleep(ctx, time.Until(at))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -3,8 +3,8 @@ package parser
 import "regexp"
 
 var (
-	// 大类正则：匹配 "第X大类 数字 (GBM code) Name"
-	MajorRegex = regexp.MustCompile(`第(.+?)大类(\d+)\(GBM([^\)]+)\)(.+)`)
+	// 大类正则：匹配 "第X大类 数字 (GBM code) Name"，X 为中文或阿拉伯数字，编号可缺失
+	MajorRegex = regexp.MustCompile(`第(.{1,3}?)大类(\d*)\(GBM([^\)]+)\)(.+)`)
 
 	// 中类正则：匹配 "数字-数字 (GBM code) Name"
 	MiddleRegex = regexp.MustCompile(`(\d+-\d+)\(GBM([^\)]+)\)`)
@@ -15,6 +15,35 @@ var (
 	// 细类代码正则：匹配四级代码格式
 	DetailCodeRegex = regexp.MustCompile(`\d+-\d+-\d+-\d+`)
 
+	// 职业标识正则：匹配名称末尾的 "L"(绿色职业)、"S"(数字职业) 或 "L/S"，含全角写法
+	MarkerRegex = regexp.MustCompile(`(?:^|[^A-Za-z0-9Ａ-Ｚａ-ｚ０-９/／\s])\s*([LSＬＳ](?:\s*[/／]\s*[LSＬＳ])?)\s*$`)
+
+	// 编码上下文正则(在 NFKC 后的文本上匹配)：形如 "1-01-00-01" 的代码，容许 OCR 误识别的字符、各类横线及横线两侧的空格
+	CodeContextRegex = regexp.MustCompile(`[0-9OoIl|]{1,2}(?:[ \t]*[-‐‑‒–—―−一][ \t]*[0-9OoIl|]{2}){1,4}`)
+
+	// GBM 编码上下文正则，包括两侧的括号；第 2 组为编码
+	GBMContextRegex = regexp.MustCompile(`(\(?[ \t]*GBM)([ \t]*[0-9OoIl|][0-9OoIl| \t]*)(\)?)`)
+
+	// 大类编号上下文正则：匹配 "大类" 后的编号
+	MajorCodeContextRegex = regexp.MustCompile(`大类[ \t]*[0-9]+`)
+
+	// 职业描述中细类标题：编号(可带 GBM)后接名称，名称也可能在下一行
+	DescriptionHeaderRegex = regexp.MustCompile(`^(\d-\d{2}-\d{2}-\d{2})(?:\s*[(（]?\s*GBM\s*\d+\s*[)）]?)?\s*(.*)$`)
+
+	// 职业描述中的大类、中类、小类标题，出现时结束当前细类
+	DescriptionHeadingRegex = regexp.MustCompile(`^(?:\d-\d{2}(?:-\d{2})?(?:\s|[(（]|$)|第.{1,3}大类)`)
+
+	// 工种编号：细类编号后追加两位
+	SubTypeCodeRegex = regexp.MustCompile(`^(\d-\d{2}-\d{2}-\d{2}-\d{2})\s*(.*)$`)
+
+	// 描述小节标题
+	DefinitionLabelRegex = regexp.MustCompile(`^定\s*义\s*[:：]\s*(.*)$`)
+	TasksLabelRegex      = regexp.MustCompile(`^主\s*要\s*工\s*作\s*任\s*务\s*[:：]?\s*(.*)$`)
+	SubTypesLabelRegex   = regexp.MustCompile(`^(?:本职业包括下列工种|下列工种归入本职业)[^:：]*[:：]?\s*(.*)$`)
+
+	// 主要工作任务的序号，如 "1."、"2、"、"（3）"
+	TaskItemRegex = regexp.MustCompile(`^(?:\d{1,2}\s*[.．、]|[（(]\s*\d{1,2}\s*[)）])\s*(.*)$`)
+
 	// 中文字符正则
 	ChineseRegex = regexp.MustCompile(`[\p{Han}]+`)
 )
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/solisamicus/occstructor/internal/corrections"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/validator"
+)
+
+// ParseReport 一次解析的结构化报告，供 CI 断言及人工复查
+type ParseReport struct {
+	Edition           string               `json:"edition"`
+	Files             []string             `json:"files"`
+	StartedAt         time.Time            `json:"started_at"`
+	DurationMS        int64                `json:"duration_ms"`
+	Counts            LevelCounts          `json:"counts"`
+	Sheets            []SheetReport        `json:"sheets"`
+	SkippedRows       []SkippedRow         `json:"skipped_rows"`
+	Mismatches        []*Mismatch          `json:"mismatches"`
+	Substitutions     []SubstitutionRecord `json:"substitutions"`
+	Conflicts         []*model.SeqConflict `json:"conflicts"`
+	OrdinalMismatches []OrdinalMismatch    `json:"ordinal_mismatches"`
+	Continuations     []ContinuationRecord `json:"continuations"`
+	SkipRules         []SkipRuleHits       `json:"skip_rules"`
+	LLM               LLMUsage             `json:"llm"`
+	DerivedGBM        int                  `json:"derived_gbm"`
+	Corrections       *corrections.Report  `json:"corrections,omitempty"`
+	Validation        *validator.Report    `json:"validation,omitempty"`
+}
+
+type LevelCounts struct {
+	Major  int `json:"major"`
+	Middle int `json:"middle"`
+	Minor  int `json:"minor"`
+	Detail int `json:"detail"`
+
+	SubType      int `json:"sub_type"`     // 工种
+	Descriptions int `json:"descriptions"` // 职业描述部分解析出的细类
+}
+
+type SheetReport struct {
+	File       string `json:"file"`
+	Sheet      string `json:"sheet"`
+	Rows       int    `json:"rows"`
+	Layout     string `json:"layout"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type SkippedRow struct {
+	File   string `json:"file"`
+	Sheet  string `json:"sheet"`
+	Row    int    `json:"row"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+type SubstitutionRecord struct {
+	File  string `json:"file"`
+	Sheet string `json:"sheet"`
+	Cell  string `json:"cell"`
+	CodeSubstitution
+}
+
+// LLMUsage 大模型调用统计
+type LLMUsage struct {
+	Calls            int   `json:"calls"`
+	Failures         int   `json:"failures"`
+	Fallbacks        int   `json:"fallbacks"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	CacheHits        int   `json:"cache_hits"`
+	CacheMisses      int   `json:"cache_misses"`
+	Reprompts        int   `json:"reprompts"` // 名称数量不符或无法解析时的重新提问次数
+	Retries          int   `json:"retries"`   // 限流、服务端错误或超时后的重试次数
+	// 接口拒绝 response_format 后降级结构化输出的次数
+	FormatFallbacks int           `json:"format_fallbacks"`
+	Recovery        RecoveryStats `json:"recovery"`
+}
+
+// RecoveryStats 从模型回答中取出名称数组的方式统计，一次回答可能同时用到多种修复
+type RecoveryStats struct {
+	Direct int `json:"direct"` // 回答本身就是要求的 {"names": [...]} 对象(或 JSON 数组)，无需修复
+	Fenced int `json:"fenced"` // 去掉了 Markdown 代码块
+	Prose  int `json:"prose"`  // 从说明文字中找到了 JSON
+	Object int `json:"object"` // 从 titles、result 等 names 以外的字段中取出数组
+	Failed int `json:"failed"` // 没有找到可用的名称数组
+}
+
+func (s *RecoveryStats) add(other RecoveryStats) {
+	s.Direct += other.Direct
+	s.Fenced += other.Fenced
+	s.Prose += other.Prose
+	s.Object += other.Object
+	s.Failed += other.Failed
+}
+
+func (u *LLMUsage) add(other LLMUsage) {
+	u.Calls += other.Calls
+	u.Failures += other.Failures
+	u.Fallbacks += other.Fallbacks
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CacheHits += other.CacheHits
+	u.CacheMisses += other.CacheMisses
+	u.Reprompts += other.Reprompts
+	u.Retries += other.Retries
+	u.FormatFallbacks += other.FormatFallbacks
+	u.Recovery.add(other.Recovery)
+}
+
+func newParseReport(files ...string) *ParseReport {
+	// 空列表输出为 [] 而不是 null，便于 CI 断言
+	return &ParseReport{
+		Files:         files,
+		StartedAt:     time.Now(),
+		Sheets:        []SheetReport{},
+		SkippedRows:   []SkippedRow{},
+		Mismatches:    []*Mismatch{},
+		Substitutions: []SubstitutionRecord{},
+		Conflicts:     []*model.SeqConflict{},
+
+		OrdinalMismatches: []OrdinalMismatch{},
+		Continuations:     []ContinuationRecord{},
+	}
+}
+
+// finish 根据最终结果统计各层级数量并记录耗时
+func (r *ParseReport) finish(result *model.ParseResult) {
+	r.Counts = LevelCounts{
+		Major:  len(result.Majors),
+		Middle: len(result.Middles),
+		Minor:  len(result.Minors),
+		Detail: len(result.SubMinors),
+
+		SubType:      len(result.SubTypes),
+		Descriptions: len(result.Descriptions),
+	}
+	if result.Conflicts != nil {
+		r.Conflicts = result.Conflicts
+	}
+	r.DurationMS = time.Since(r.StartedAt).Milliseconds()
+}
+
+func (r *ParseReport) merge(other *ParseReport) {
+	r.Sheets = append(r.Sheets, other.Sheets...)
+	r.SkippedRows = append(r.SkippedRows, other.SkippedRows...)
+	r.Mismatches = append(r.Mismatches, other.Mismatches...)
+	r.Substitutions = append(r.Substitutions, other.Substitutions...)
+	r.OrdinalMismatches = append(r.OrdinalMismatches, other.OrdinalMismatches...)
+	r.Continuations = append(r.Continuations, other.Continuations...)
+	for i := range r.SkipRules {
+		if i < len(other.SkipRules) {
+			r.SkipRules[i].Hits += other.SkipRules[i].Hits
+		}
+	}
+
+	r.LLM.add(other.LLM)
+}
+
+// WriteJSON 将报告写入 JSON 文件
+func (r *ParseReport) WriteJSON(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
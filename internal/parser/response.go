@@ -0,0 +1,136 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// 结构化输出模式，对应配置 ai.structured_output
+const (
+	StructuredOff        = "off"         // 只靠提示词约束格式
+	StructuredJSONObject = "json_object" // response_format: json_object
+	StructuredJSONSchema = "json_schema" // response_format: json_schema，接口不支持时依次降级
+)
+
+// structuredModes 按级别排列，降级时从 json_schema 依次退到 off
+var structuredModes = []string{StructuredOff, StructuredJSONObject, StructuredJSONSchema}
+
+// structuredLevel 返回模式在 structuredModes 中的级别，空值视为 json_schema
+func structuredLevel(mode string) (int32, bool) {
+	if mode == "" {
+		mode = StructuredJSONSchema
+	}
+	for i, m := range structuredModes {
+		if m == mode {
+			return int32(i), true
+		}
+	}
+	return 0, false
+}
+
+// namesSchema json_schema 模式下要求的回答格式
+var namesSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"names": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		},
+	},
+	"required":             []string{"names"},
+	"additionalProperties": false,
+}
+
+// 对象形式的回答中可能存放名称数组的字段
+var nameListKeys = []string{"names", "titles", "job_titles", "result", "results"}
+
+var fenceRegex = regexp.MustCompile("(?s)```[A-Za-z]*[ \t]*\n?(.*?)```")
+
+// recovery 一次解析用到的修复方式；object 表示名称不在要求的 names 字段中
+type recovery struct {
+	fenced, prose, object bool
+}
+
+// count 累计到统计，没有用到任何修复时记为 direct
+func (r recovery) count(stats *RecoveryStats) {
+	if !r.fenced && !r.prose && !r.object {
+		stats.Direct++
+	}
+	if r.fenced {
+		stats.Fenced++
+	}
+	if r.prose {
+		stats.Prose++
+	}
+	if r.object {
+		stats.Object++
+	}
+}
+
+// decodeNameList 从模型回答中取出名称数组：容许 Markdown 代码块、前后的说明文字以及 names 以外字段的对象
+func decodeNameList(content string) ([]string, recovery, error) {
+	var rec recovery
+
+	text := strings.TrimSpace(content)
+	if m := fenceRegex.FindStringSubmatch(text); m != nil {
+		text = strings.TrimSpace(m[1])
+		rec.fenced = true
+	}
+
+	if names, otherField, ok := decodeJSONNames([]byte(text)); ok {
+		rec.object = otherField
+		return names, rec, nil
+	}
+
+	// 依次尝试说明文字中每个以 [ 或 { 开始的 JSON 值
+	for i, r := range text {
+		if r != '[' && r != '{' {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&raw); err != nil {
+			continue
+		}
+		if names, otherField, ok := decodeJSONNames(raw); ok {
+			rec.prose, rec.object = true, otherField
+			return names, rec, nil
+		}
+	}
+
+	return nil, rec, errors.New("no JSON array of strings found")
+}
+
+// decodeJSONNames 解析字符串数组，或取出对象中的名称数组字段；
+// 提示词在各结构化输出模式下都要求 {"names": [...]}，名称取自其他字段时第二个返回值为 true
+func decodeJSONNames(data []byte) ([]string, bool, bool) {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		return names, false, true
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, false, false
+	}
+	for _, key := range nameListKeys {
+		if raw, ok := obj[key]; ok && json.Unmarshal(raw, &names) == nil {
+			return names, key != "names", true
+		}
+	}
+
+	var found []string
+	count := 0
+	for _, raw := range obj {
+		var list []string
+		if json.Unmarshal(raw, &list) == nil {
+			found = list
+			count++
+		}
+	}
+	if count == 1 {
+		return found, true, true
+	}
+	return nil, false, false
+}
@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/solisamicus/occstructor/internal/config"
+	"github.com/xuri/excelize/v2"
+)
+
+// skipRule 编译后的跳过规则
+type skipRule struct {
+	config.SkipRule
+	column int // 从 0 开始的列号，-1 表示所有列
+	regex  *regexp.Regexp
+	rows   map[int]bool
+}
+
+// SkipRuleHits 一条跳过规则在本次解析中命中的行数
+type SkipRuleHits struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Pattern string `json:"pattern,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Hits    int    `json:"hits"`
+}
+
+func compileSkipRules(rules []config.SkipRule) ([]*skipRule, error) {
+	var compiled []*skipRule
+
+	for i, rule := range rules {
+		r := &skipRule{SkipRule: rule, column: -1}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule%d", i+1)
+		}
+
+		if rule.Column != "" {
+			col, err := excelize.ColumnNameToNumber(strings.TrimSpace(rule.Column))
+			if err != nil {
+				return nil, fmt.Errorf("skip rule %s: invalid column %q: %w", r.Name, rule.Column, err)
+			}
+			r.column = col - 1
+		}
+
+		if len(rule.Rows) > 0 {
+			r.rows = make(map[int]bool, len(rule.Rows))
+			for _, num := range rule.Rows {
+				r.rows[num] = true
+			}
+		}
+
+		switch rule.Type {
+		case config.SkipExact, config.SkipContains:
+			if rule.Pattern == "" {
+				return nil, fmt.Errorf("skip rule %s: pattern is required for type %s", r.Name, rule.Type)
+			}
+		case config.SkipRegex:
+			// 空正则会匹配所有单元格
+			if rule.Pattern == "" {
+				return nil, fmt.Errorf("skip rule %s: pattern is required for type %s", r.Name, rule.Type)
+			}
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("skip rule %s: invalid pattern %q: %w", r.Name, rule.Pattern, err)
+			}
+			r.regex = re
+		case config.SkipRow:
+			if len(rule.Rows) == 0 {
+				return nil, fmt.Errorf("skip rule %s: rows is required for type %s", r.Name, rule.Type)
+			}
+		default:
+			return nil, fmt.Errorf("skip rule %s: unknown type %q", r.Name, rule.Type)
+		}
+
+		compiled = append(compiled, r)
+	}
+
+	return compiled, nil
+}
+
+// match 判断一行是否命中规则，返回命中的单元格文本
+func (r *skipRule) match(num int, cells []string) (string, bool) {
+	if r.rows != nil && !r.rows[num] {
+		return "", false
+	}
+	if r.Type == config.SkipRow {
+		return "", true
+	}
+
+	for col, text := range cells {
+		if r.column >= 0 && col != r.column {
+			continue
+		}
+		if r.matchCell(strings.TrimSpace(text)) {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (r *skipRule) matchCell(text string) bool {
+	switch r.Type {
+	case config.SkipExact:
+		return strings.Join(strings.Fields(text), "") == strings.Join(strings.Fields(r.Pattern), "")
+	case config.SkipContains:
+		return strings.Contains(text, r.Pattern)
+	case config.SkipRegex:
+		return r.regex.MatchString(text)
+	}
+	return false
+}
+
+// skipRow 返回第一条命中的规则序号及单元格文本，未命中时返回 -1
+func (p *ExcelParser) skipRow(num int, cells []string) (int, string) {
+	for i, rule := range p.skipRules {
+		if text, ok := rule.match(num, cells); ok {
+			return i, text
+		}
+	}
+	return -1, ""
+}
+
+// newSkipRuleHits 为每条规则创建计数，规则顺序与配置一致
+func (p *ExcelParser) newSkipRuleHits() []SkipRuleHits {
+	hits := make([]SkipRuleHits, len(p.skipRules))
+	for i, rule := range p.skipRules {
+		hits[i] = SkipRuleHits{
+			Name:    rule.Name,
+			Type:    rule.Type,
+			Pattern: rule.Pattern,
+			Column:  rule.Column,
+		}
+	}
+	return hits
+}
+
+// printSkipRuleHits 打印各跳过规则的命中次数
+func printSkipRuleHits(hits []SkipRuleHits) {
+	for _, h := range hits {
+		fmt.Printf("Skip rule %-16s %-8s %-24s %d rows\n", h.Name, h.Type, h.Pattern, h.Hits)
+	}
+}
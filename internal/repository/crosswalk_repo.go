@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/solisamicus/occstructor/internal/crosswalk"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+// CrosswalkRepository 读写对照表，映射跨越多个版本，因此不限定 edition
+type CrosswalkRepository struct {
+	db *database.DB
+}
+
+func NewCrosswalkRepository(db *database.DB) *CrosswalkRepository {
+	return &CrosswalkRepository{db: db}
+}
+
+func (r *CrosswalkRepository) BatchUpsert(entries []crosswalk.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO occupation_crosswalk (source_edition, source_seq, target_system, target_code, relation, note) 
+			  VALUES (?, ?, ?, ?, ?, ?) 
+			  ON DUPLICATE KEY UPDATE 
+			  relation = VALUES(relation), 
+			  note = VALUES(note)`
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.Exec(e.SourceEdition, e.SourceSeq, e.TargetSystem, e.TargetCode, e.Relation, e.Note); err != nil {
+			return fmt.Errorf("failed to insert mapping %s/%s → %s/%s: %w", e.SourceEdition, e.SourceSeq, e.TargetSystem, e.TargetCode, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAll 返回全部对照记录
+func (r *CrosswalkRepository) GetAll() ([]crosswalk.Entry, error) {
+	query := `SELECT source_edition, source_seq, target_system, target_code, relation, note 
+			  FROM occupation_crosswalk 
+			  ORDER BY source_edition, source_seq, target_system, target_code`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crosswalk: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []crosswalk.Entry
+	for rows.Next() {
+		var e crosswalk.Entry
+		var note sql.NullString
+		if err := rows.Scan(&e.SourceEdition, &e.SourceSeq, &e.TargetSystem, &e.TargetCode, &e.Relation, &note); err != nil {
+			return nil, fmt.Errorf("failed to scan crosswalk: %w", err)
+		}
+		e.Note = note.String
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetMappings 按编号返回某版本的全部映射：以该版本为源的正向映射，
+// 以及其他版本映射到该版本的反向映射(关系取反，如 broader 变为 narrower)。seq 为空时返回全部编号
+func (r *CrosswalkRepository) GetMappings(edition, seq string) (map[string][]model.Mapping, error) {
+	query := `SELECT source_seq, target_system, target_code, relation, FALSE FROM occupation_crosswalk 
+			  WHERE source_edition = ? AND (? = '' OR source_seq = ?) 
+			  UNION ALL 
+			  SELECT target_code, source_edition, source_seq, relation, TRUE FROM occupation_crosswalk 
+			  WHERE target_system = ? AND (? = '' OR target_code = ?) 
+			  ORDER BY 1, 2, 3`
+
+	rows, err := r.db.Query(query, edition, seq, seq, edition, seq, seq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query mappings: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]model.Mapping)
+	for rows.Next() {
+		var key string
+		var reversed bool
+		var m model.Mapping
+		if err := rows.Scan(&key, &m.System, &m.Code, &m.Relation, &reversed); err != nil {
+			return nil, fmt.Errorf("failed to scan mapping: %w", err)
+		}
+		if reversed {
+			m.Relation = crosswalk.InverseRelation(m.Relation)
+		}
+		result[key] = append(result[key], m)
+	}
+
+	return result, rows.Err()
+}
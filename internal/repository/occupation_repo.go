@@ -7,32 +7,70 @@ import (
 	"github.com/solisamicus/occstructor/pkg/database"
 )
 
+// OccupationRepository 读写单个大典版本的数据，所有查询都限定在 edition 内
 type OccupationRepository struct {
-	db *database.DB
+	db      *database.DB
+	edition string
 }
 
-func NewOccupationRepository(db *database.DB) *OccupationRepository {
-	return &OccupationRepository{db: db}
+func NewOccupationRepository(db *database.DB, edition string) *OccupationRepository {
+	return &OccupationRepository{db: db, edition: edition}
+}
+
+func (r *OccupationRepository) Edition() string {
+	return r.edition
 }
 
 func (r *OccupationRepository) BatchInsert(nodes []*model.OccupationNode) error {
-	if len(nodes) == 0 {
-		return nil
+	_, err := r.ReplaceNodes(nil, nodes)
+	return err
+}
+
+// ReplaceNodes 在同一事务中删除 deleted 中的编号(子节点由外键级联删除)并写入 nodes，
+// 任一步失败时全部回滚，返回删除的记录数
+func (r *OccupationRepository) ReplaceNodes(deleted []string, nodes []*model.OccupationNode) (int64, error) {
+	if len(deleted) == 0 && len(nodes) == 0 {
+		return 0, nil
 	}
 
 	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	query := `INSERT INTO occupations (seq, gbm, name, level, parent_seq) 
-			  VALUES (?, ?, ?, ?, ?) 
+	total, err := r.deleteSeqs(tx, deleted)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.insertNodes(tx, nodes); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *OccupationRepository) insertNodes(tx *sql.Tx, nodes []*model.OccupationNode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	// 未解析职业描述时 definition 为 NULL，保留库中已有的定义
+	query := `INSERT INTO occupations (edition, seq, gbm, name, level, parent_seq, is_green, is_digital, definition) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) 
 			  ON DUPLICATE KEY UPDATE 
 			  gbm = VALUES(gbm), 
 			  name = VALUES(name), 
 			  level = VALUES(level), 
-			  parent_seq = VALUES(parent_seq)`
+			  parent_seq = VALUES(parent_seq), 
+			  is_green = VALUES(is_green), 
+			  is_digital = VALUES(is_digital), 
+			  definition = COALESCE(VALUES(definition), definition)`
 
 	stmt, err := tx.Prepare(query)
 	if err != nil {
@@ -41,23 +79,212 @@ func (r *OccupationRepository) BatchInsert(nodes []*model.OccupationNode) error
 	defer stmt.Close()
 
 	for _, node := range nodes {
-		_, err := stmt.Exec(node.Seq, node.GBM, node.Name, node.Level, node.ParentSeq)
+		var definition *string
+		if node.Definition != "" {
+			definition = &node.Definition
+		}
+		_, err := stmt.Exec(r.edition, node.Seq, node.GBM, node.Name, node.Level, node.ParentSeq, node.Green, node.Digital, definition)
 		if err != nil {
 			return fmt.Errorf("failed to insert node %s: %w", node.Seq, err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if err := r.insertProvenance(tx, nodes); err != nil {
+		return err
+	}
+
+	return r.replaceTasks(tx, nodes)
+}
+
+func (r *OccupationRepository) insertProvenance(tx *sql.Tx, nodes []*model.OccupationNode) error {
+	query := `INSERT INTO occupation_provenance (edition, seq, file_name, sheet_name, row_num, column_name, raw_text) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?) 
+			  ON DUPLICATE KEY UPDATE 
+			  file_name = VALUES(file_name), 
+			  sheet_name = VALUES(sheet_name), 
+			  row_num = VALUES(row_num), 
+			  column_name = VALUES(column_name), 
+			  raw_text = VALUES(raw_text)`
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare provenance statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, node := range nodes {
+		if node.Source == nil {
+			continue
+		}
+		src := node.Source
+		if _, err := stmt.Exec(r.edition, node.Seq, src.File, src.Sheet, src.Row, src.Column, src.RawText); err != nil {
+			return fmt.Errorf("failed to insert provenance for %s: %w", node.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// replaceTasks 用解析出的任务列表整体替换节点原有的任务，没有任务的节点保持不变
+func (r *OccupationRepository) replaceTasks(tx *sql.Tx, nodes []*model.OccupationNode) error {
+	del, err := tx.Prepare(`DELETE FROM occupation_tasks WHERE edition = ? AND seq = ?`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare task statement: %w", err)
+	}
+	defer del.Close()
+
+	ins, err := tx.Prepare(`INSERT INTO occupation_tasks (edition, seq, position, task) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare task statement: %w", err)
+	}
+	defer ins.Close()
+
+	for _, node := range nodes {
+		if len(node.Tasks) == 0 {
+			continue
+		}
+		if _, err := del.Exec(r.edition, node.Seq); err != nil {
+			return fmt.Errorf("failed to delete tasks of %s: %w", node.Seq, err)
+		}
+		for i, task := range node.Tasks {
+			if _, err := ins.Exec(r.edition, node.Seq, i+1, task); err != nil {
+				return fmt.Errorf("failed to insert task %d of %s: %w", i+1, node.Seq, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// getTasks 按编号返回当前版本的任务列表，按序号排列
+func (r *OccupationRepository) getTasks() (map[string][]string, error) {
+	rows, err := r.db.Query(`SELECT seq, task FROM occupation_tasks WHERE edition = ? ORDER BY seq, position`, r.edition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make(map[string][]string)
+	for rows.Next() {
+		var seq, task string
+		if err := rows.Scan(&seq, &task); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks[seq] = append(tasks[seq], task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// GetAll 按层级、编号顺序返回当前版本的全部记录
+func (r *OccupationRepository) GetAll() ([]*model.OccupationNode, error) {
+	query := `SELECT id, edition, seq, gbm, name, level, parent_seq, is_green, is_digital, definition, created_at, updated_at 
+			  FROM occupations 
+			  WHERE edition = ?
+			  ORDER BY level, seq`
+
+	rows, err := r.db.Query(query, r.edition)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var occupations []*model.OccupationNode
+	for rows.Next() {
+		occ := &model.OccupationNode{}
+		var parentSeq sql.NullString
+		var gbm, definition sql.NullString
+
+		err := rows.Scan(&occ.ID, &occ.Edition, &occ.Seq, &gbm, &occ.Name,
+			&occ.Level, &parentSeq, &occ.Green, &occ.Digital, &definition, &occ.CreatedAt, &occ.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if gbm.Valid {
+			occ.GBM = gbm.String
+		}
+		if parentSeq.Valid {
+			occ.ParentSeq = &parentSeq.String
+		}
+		occ.Definition = definition.String
+
+		occupations = append(occupations, occ)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.getTasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, occ := range occupations {
+		occ.Tasks = tasks[occ.Seq]
+	}
+
+	return occupations, nil
+}
+
+// GetProvenance 按职业编号返回来源信息
+func (r *OccupationRepository) GetProvenance() (map[string]*model.Provenance, error) {
+	query := `SELECT seq, file_name, sheet_name, row_num, column_name, raw_text FROM occupation_provenance WHERE edition = ?`
+
+	rows, err := r.db.Query(query, r.edition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provenance: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]*model.Provenance)
+	for rows.Next() {
+		var seq string
+		var file, rawText sql.NullString
+		src := &model.Provenance{}
+		if err := rows.Scan(&seq, &file, &src.Sheet, &src.Row, &src.Column, &rawText); err != nil {
+			return nil, fmt.Errorf("failed to scan provenance: %w", err)
+		}
+		src.File = file.String
+		src.RawText = rawText.String
+		result[seq] = src
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteBySeqs 删除指定编号的记录，子节点由外键级联删除
+func (r *OccupationRepository) DeleteBySeqs(seqs []string) (int64, error) {
+	return r.ReplaceNodes(seqs, nil)
+}
+
+func (r *OccupationRepository) deleteSeqs(tx *sql.Tx, seqs []string) (int64, error) {
+	if len(seqs) == 0 {
+		return 0, nil
+	}
+
+	stmt, err := tx.Prepare(`DELETE FROM occupations WHERE edition = ? AND seq = ?`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var total int64
+	for _, seq := range seqs {
+		res, err := stmt.Exec(r.edition, seq)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete node %s: %w", seq, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	return total, nil
+}
+
 func (r *OccupationRepository) GetStats() (map[int]int, error) {
-	query := `SELECT level, COUNT(*) FROM occupations GROUP BY level ORDER BY level`
+	query := `SELECT level, COUNT(*) FROM occupations WHERE edition = ? GROUP BY level ORDER BY level`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, r.edition)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query stats: %w", err)
 	}
@@ -75,6 +302,46 @@ func (r *OccupationRepository) GetStats() (map[int]int, error) {
 	return stats, nil
 }
 
-func (r *OccupationRepository) QueryRaw(query string) (*sql.Rows, error) {
-	return r.db.Query(query)
+// SeqSet 返回当前版本的全部编号
+func (r *OccupationRepository) SeqSet() (map[string]bool, error) {
+	rows, err := r.db.Query(`SELECT seq FROM occupations WHERE edition = ?`, r.edition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query seqs: %w", err)
+	}
+	defer rows.Close()
+
+	seqs := make(map[string]bool)
+	for rows.Next() {
+		var seq string
+		if err := rows.Scan(&seq); err != nil {
+			return nil, fmt.Errorf("failed to scan seq: %w", err)
+		}
+		seqs[seq] = true
+	}
+
+	return seqs, rows.Err()
+}
+
+// ListEditions 返回数据库中已导入的全部版本
+func (r *OccupationRepository) ListEditions() ([]string, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT edition FROM occupations ORDER BY edition`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query editions: %w", err)
+	}
+	defer rows.Close()
+
+	var editions []string
+	for rows.Next() {
+		var edition string
+		if err := rows.Scan(&edition); err != nil {
+			return nil, fmt.Errorf("failed to scan edition: %w", err)
+		}
+		editions = append(editions, edition)
+	}
+
+	return editions, rows.Err()
+}
+
+func (r *OccupationRepository) QueryRaw(query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.Query(query, args...)
 }
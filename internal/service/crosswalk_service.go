@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/solisamicus/occstructor/internal/crosswalk"
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/repository"
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+// CrosswalkService 导入、校验和查询对照表，校验时按需加载各版本的编号
+type CrosswalkService struct {
+	db        *database.DB
+	crosswalk *repository.CrosswalkRepository
+	seqs      map[string]map[string]bool
+}
+
+func NewCrosswalkService(db *database.DB) *CrosswalkService {
+	return &CrosswalkService{
+		db:        db,
+		crosswalk: repository.NewCrosswalkRepository(db),
+		seqs:      make(map[string]map[string]bool),
+	}
+}
+
+// Validate 检查条目引用的编号在 occupations 中均存在
+func (s *CrosswalkService) Validate(entries []crosswalk.Entry) ([]crosswalk.Problem, error) {
+	for _, e := range entries {
+		editions := []string{e.SourceEdition}
+		if e.IsEdition() {
+			editions = append(editions, e.TargetSystem)
+		}
+		for _, edition := range editions {
+			if _, ok := s.seqs[edition]; ok {
+				continue
+			}
+			seqs, err := repository.NewOccupationRepository(s.db, edition).SeqSet()
+			if err != nil {
+				return nil, err
+			}
+			s.seqs[edition] = seqs
+		}
+	}
+
+	return crosswalk.Validate(entries, func(edition, seq string) bool {
+		return s.seqs[edition][seq]
+	}), nil
+}
+
+// Import 校验并写入对照表，存在无效引用时不写入
+func (s *CrosswalkService) Import(entries []crosswalk.Entry) ([]crosswalk.Problem, error) {
+	problems, err := s.Validate(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate crosswalk: %w", err)
+	}
+	if len(problems) > 0 {
+		return problems, fmt.Errorf("%d mappings reference unknown seqs, nothing imported", len(problems))
+	}
+
+	if err := s.crosswalk.BatchUpsert(entries); err != nil {
+		return nil, fmt.Errorf("failed to save crosswalk: %w", err)
+	}
+
+	fmt.Printf("Successfully imported %d mappings\n", len(entries))
+	return nil, nil
+}
+
+// ValidateStored 重新校验已入库的对照表，目标版本重新导入后可能出现失效的映射
+func (s *CrosswalkService) ValidateStored() ([]crosswalk.Problem, error) {
+	entries, err := s.crosswalk.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Validating %d stored mappings\n", len(entries))
+	return s.Validate(entries)
+}
+
+// Lookup 查询某版本某编号的全部映射(含反向映射)
+func (s *CrosswalkService) Lookup(edition, seq string) ([]model.Mapping, error) {
+	mappings, err := s.crosswalk.GetMappings(edition, seq)
+	if err != nil {
+		return nil, err
+	}
+	return mappings[seq], nil
+}
@@ -1,11 +1,11 @@
 package service
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/solisamicus/occstructor/internal/model"
@@ -13,20 +13,24 @@ import (
 )
 
 type ExportService struct {
-	repo *repository.OccupationRepository
+	repo      *repository.OccupationRepository
+	crosswalk *repository.CrosswalkRepository
 }
 
-func NewExportService(repo *repository.OccupationRepository) *ExportService {
-	return &ExportService{repo: repo}
+func NewExportService(repo *repository.OccupationRepository, crosswalk *repository.CrosswalkRepository) *ExportService {
+	return &ExportService{repo: repo, crosswalk: crosswalk}
 }
 
 type ExportOptions struct {
-	OutputPath   string `json:"output_path"`
-	Format       string `json:"format"` // "tree" 或 "flat"
-	IncludeStats bool   `json:"include_stats"`
+	OutputPath     string `json:"output_path"`
+	Format         string `json:"format"` // "tree" 或 "flat"
+	IncludeStats   bool   `json:"include_stats"`
+	WithProvenance bool   `json:"with_provenance"` // 附带源工作簿中的位置
+	WithMappings   bool   `json:"with_mappings"`   // 附带 ISCO-08 及其他版本的对照编码
 }
 
 type ExportResult struct {
+	Edition      string       `json:"edition,omitempty"`
 	Data         interface{}  `json:"data"`
 	Stats        *ExportStats `json:"stats,omitempty"`
 	ExportedAt   time.Time    `json:"exported_at"`
@@ -34,26 +38,81 @@ type ExportResult struct {
 }
 
 type ExportStats struct {
-	MajorCount  int `json:"major_count"`
-	MiddleCount int `json:"middle_count"`
-	MinorCount  int `json:"minor_count"`
-	DetailCount int `json:"detail_count"`
+	MajorCount   int `json:"major_count"`
+	MiddleCount  int `json:"middle_count"`
+	MinorCount   int `json:"minor_count"`
+	DetailCount  int `json:"detail_count"`
+	SubTypeCount int `json:"sub_type_count,omitempty"`
 }
 
 func (s *ExportService) ExportToJSON(options *ExportOptions) error {
-	occupations, err := s.getAllOccupations()
+	occupations, err := s.repo.GetAll()
 	if err != nil {
 		return fmt.Errorf("failed to get occupations: %w", err)
 	}
 
-	fmt.Printf("Retrieved %d occupation records from database\n", len(occupations))
+	fmt.Printf("Retrieved %d occupation records of edition %s from database\n", len(occupations), s.repo.Edition())
+	if len(occupations) == 0 {
+		s.warnUnknownEdition()
+	}
+
+	if options.WithProvenance {
+		if err := s.attachProvenance(occupations); err != nil {
+			return fmt.Errorf("failed to get provenance: %w", err)
+		}
+	}
+
+	if options.WithMappings {
+		if err := s.attachMappings(occupations); err != nil {
+			return fmt.Errorf("failed to get mappings: %w", err)
+		}
+	}
+
+	result := buildExportResult(occupations, options.Format)
+	result.Edition = s.repo.Edition()
+
+	if options.IncludeStats {
+		stats, err := s.getExportStats()
+		if err != nil {
+			return fmt.Errorf("failed to get stats: %w", err)
+		}
+		result.Stats = stats
+	}
+
+	return writeExportResult(result, options.OutputPath)
+}
 
+// ExportNodes 直接导出内存中的节点(如 dry-run 的解析结果)，不访问数据库，统计信息按节点层级计算
+func ExportNodes(occupations []*model.OccupationNode, options *ExportOptions) error {
+	if !options.WithProvenance {
+		stripped := make([]*model.OccupationNode, len(occupations))
+		for i, occ := range occupations {
+			copied := *occ
+			copied.Source = nil
+			stripped[i] = &copied
+		}
+		occupations = stripped
+	}
+
+	result := buildExportResult(occupations, options.Format)
+	if len(occupations) > 0 {
+		result.Edition = occupations[0].Edition
+	}
+
+	if options.IncludeStats {
+		result.Stats = countLevels(occupations)
+	}
+
+	return writeExportResult(result, options.OutputPath)
+}
+
+func buildExportResult(occupations []*model.OccupationNode, format string) *ExportResult {
 	result := &ExportResult{
 		ExportedAt:   time.Now(),
 		TotalRecords: len(occupations),
 	}
 
-	switch options.Format {
+	switch format {
 	case "tree":
 		tree := model.BuildOccupationTree(occupations)
 		result.Data = tree
@@ -67,15 +126,11 @@ func (s *ExportService) ExportToJSON(options *ExportOptions) error {
 		fmt.Println("Built tree structure (default)")
 	}
 
-	if options.IncludeStats {
-		stats, err := s.getExportStats()
-		if err != nil {
-			return fmt.Errorf("failed to get stats: %w", err)
-		}
-		result.Stats = stats
-	}
+	return result
+}
 
-	if err := os.MkdirAll(filepath.Dir(options.OutputPath), 0755); err != nil {
+func writeExportResult(result *ExportResult, outputPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
@@ -84,50 +139,65 @@ func (s *ExportService) ExportToJSON(options *ExportOptions) error {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
 
-	if err := os.WriteFile(options.OutputPath, jsonData, 0644); err != nil {
+	if err := os.WriteFile(outputPath, jsonData, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
-	fmt.Printf("Successfully exported to: %s\n", options.OutputPath)
+	fmt.Printf("Successfully exported to: %s\n", outputPath)
 	fmt.Printf("File size: %.2f KB\n", float64(len(jsonData))/1024)
 
 	return nil
 }
 
-func (s *ExportService) getAllOccupations() ([]*model.OccupationNode, error) {
-	query := `SELECT id, seq, gbm, name, level, parent_seq, created_at, updated_at 
-			  FROM occupations 
-			  ORDER BY level, seq`
-
-	rows, err := s.repo.QueryRaw(query)
+// warnUnknownEdition 导出结果为空时提示数据库中已有的版本
+func (s *ExportService) warnUnknownEdition() {
+	editions, err := s.repo.ListEditions()
 	if err != nil {
-		return nil, err
+		return
 	}
-	defer rows.Close()
+	fmt.Printf("Warning: no records for edition %s, available editions: %s\n", s.repo.Edition(), strings.Join(editions, ", "))
+}
 
-	var occupations []*model.OccupationNode
-	for rows.Next() {
-		occ := &model.OccupationNode{}
-		var parentSeq sql.NullString
-		var gbm sql.NullString
+// attachProvenance 为每条记录附加来源信息
+func (s *ExportService) attachProvenance(occupations []*model.OccupationNode) error {
+	sources, err := s.repo.GetProvenance()
+	if err != nil {
+		return err
+	}
 
-		err := rows.Scan(&occ.ID, &occ.Seq, &gbm, &occ.Name,
-			&occ.Level, &parentSeq, &occ.CreatedAt, &occ.UpdatedAt)
-		if err != nil {
-			return nil, err
+	missing := 0
+	for _, occ := range occupations {
+		if src, ok := sources[occ.Seq]; ok {
+			occ.Source = src
+		} else {
+			missing++
 		}
+	}
 
-		if gbm.Valid {
-			occ.GBM = gbm.String
-		}
-		if parentSeq.Valid {
-			occ.ParentSeq = &parentSeq.String
-		}
+	if missing > 0 {
+		fmt.Printf("Warning: %d records have no provenance\n", missing)
+	}
 
-		occupations = append(occupations, occ)
+	return nil
+}
+
+// attachMappings 为每条记录附加对照编码
+func (s *ExportService) attachMappings(occupations []*model.OccupationNode) error {
+	mappings, err := s.crosswalk.GetMappings(s.repo.Edition(), "")
+	if err != nil {
+		return err
 	}
 
-	return occupations, nil
+	mapped := 0
+	for _, occ := range occupations {
+		if m, ok := mappings[occ.Seq]; ok {
+			occ.Mappings = m
+			mapped++
+		}
+	}
+
+	fmt.Printf("Attached mappings to %d of %d records\n", mapped, len(occupations))
+	return nil
 }
 
 // getExportStats 获取导出统计信息
@@ -138,13 +208,34 @@ func (s *ExportService) getExportStats() (*ExportStats, error) {
 	}
 
 	return &ExportStats{
-		MajorCount:  stats[1],
-		MiddleCount: stats[2],
-		MinorCount:  stats[3],
-		DetailCount: stats[4],
+		MajorCount:   stats[1],
+		MiddleCount:  stats[2],
+		MinorCount:   stats[3],
+		DetailCount:  stats[4],
+		SubTypeCount: stats[5],
 	}, nil
 }
 
+// countLevels 按层级统计内存中的节点
+func countLevels(occupations []*model.OccupationNode) *ExportStats {
+	stats := &ExportStats{}
+	for _, occ := range occupations {
+		switch occ.Level {
+		case 1:
+			stats.MajorCount++
+		case 2:
+			stats.MiddleCount++
+		case 3:
+			stats.MinorCount++
+		case 4:
+			stats.DetailCount++
+		case 5:
+			stats.SubTypeCount++
+		}
+	}
+	return stats
+}
+
 // ExportMultipleFormats 导出多种格式
 func (s *ExportService) ExportMultipleFormats(baseDir string) error {
 	timestamp := time.Now().Format("20060102_150405")
@@ -153,8 +244,8 @@ func (s *ExportService) ExportMultipleFormats(baseDir string) error {
 		format   string
 		filename string
 	}{
-		{"tree", fmt.Sprintf("occupations_tree_%s.json", timestamp)},
-		{"flat", fmt.Sprintf("occupations_flat_%s.json", timestamp)},
+		{"tree", fmt.Sprintf("occupations_%s_tree_%s.json", s.repo.Edition(), timestamp)},
+		{"flat", fmt.Sprintf("occupations_%s_flat_%s.json", s.repo.Edition(), timestamp)},
 	}
 
 	for _, f := range formats {
@@ -171,3 +262,62 @@ func (s *ExportService) ExportMultipleFormats(baseDir string) error {
 
 	return nil
 }
+
+// exportedNode 同时兼容树状与扁平导出格式中的节点
+type exportedNode struct {
+	Edition   string          `json:"edition"`
+	Seq       string          `json:"seq"`
+	GBM       string          `json:"gbm"`
+	Name      string          `json:"name"`
+	Level     int             `json:"level"`
+	ParentSeq *string         `json:"parent_seq"`
+	Green     bool            `json:"green"`
+	Digital   bool            `json:"digital"`
+	Children  []*exportedNode `json:"children"`
+}
+
+// LoadExportFile 读取 ExportToJSON/ExportNodes 导出的文件(树状或扁平)，返回版本号和扁平节点
+func LoadExportFile(path string) (string, []*model.OccupationNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read export file: %w", err)
+	}
+
+	var envelope struct {
+		Edition string          `json:"edition"`
+		Data    []*exportedNode `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", nil, fmt.Errorf("failed to decode export file %s: %w", path, err)
+	}
+
+	var nodes []*model.OccupationNode
+	var flatten func(items []*exportedNode, parentSeq *string)
+	flatten = func(items []*exportedNode, parentSeq *string) {
+		for _, item := range items {
+			node := &model.OccupationNode{
+				Edition:   item.Edition,
+				Seq:       item.Seq,
+				GBM:       item.GBM,
+				Name:      item.Name,
+				Level:     item.Level,
+				ParentSeq: item.ParentSeq,
+				Green:     item.Green,
+				Digital:   item.Digital,
+			}
+			if node.ParentSeq == nil && parentSeq != nil {
+				node.ParentSeq = parentSeq
+			}
+			if node.Edition == "" {
+				node.Edition = envelope.Edition
+			}
+			nodes = append(nodes, node)
+
+			seq := item.Seq
+			flatten(item.Children, &seq)
+		}
+	}
+	flatten(envelope.Data, nil)
+
+	return envelope.Edition, nodes, nil
+}
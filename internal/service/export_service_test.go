@@ -0,0 +1,107 @@
+package service_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/repository"
+	"github.com/solisamicus/occstructor/internal/service"
+)
+
+// exportedSources 读取扁平导出文件，返回各编号的来源信息及是否出现 source 字段
+func exportedSources(t *testing.T, path string) (map[string]*model.Provenance, bool) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := make(map[string]*model.Provenance)
+	present := false
+	for _, node := range result.Data {
+		var seq string
+		json.Unmarshal(node["seq"], &seq)
+		if raw, ok := node["source"]; ok {
+			present = true
+			src := &model.Provenance{}
+			if err := json.Unmarshal(raw, src); err != nil {
+				t.Fatal(err)
+			}
+			sources[seq] = src
+		}
+	}
+	return sources, present
+}
+
+func provenanceNodes() []*model.OccupationNode {
+	major := "1"
+	return []*model.OccupationNode{
+		{Seq: "1", GBM: "10000", Name: "党的机关、国家机关、群众团体和社会组织、企事业单位负责人", Level: 1,
+			Source: &model.Provenance{File: "vol1.xlsx", Sheet: "Sheet1", Row: 3, Column: "A", RawText: "第一大类\n(GBM 10000)"}},
+		{Seq: "1-01", GBM: "10100", Name: "中国共产党机关负责人", Level: 2, ParentSeq: &major,
+			Source: &model.Provenance{File: "vol1.xlsx", Sheet: "Sheet1", Row: 4, Column: "B", RawText: "1-01 (GBM 10100)"}},
+	}
+}
+
+func TestExportProvenance(t *testing.T) {
+	db, store := openFakeDB(t)
+	repo := repository.NewOccupationRepository(db, "2022")
+	if err := repo.BatchInsert(provenanceNodes()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.provenance) != 2 {
+		t.Fatalf("got %d provenance rows, want 2", len(store.provenance))
+	}
+
+	dir := t.TempDir()
+	s := service.NewExportService(repo, nil)
+
+	path := filepath.Join(dir, "with.json")
+	if err := s.ExportToJSON(&service.ExportOptions{OutputPath: path, Format: "flat", WithProvenance: true}); err != nil {
+		t.Fatal(err)
+	}
+	sources, _ := exportedSources(t, path)
+	want := &model.Provenance{File: "vol1.xlsx", Sheet: "Sheet1", Row: 4, Column: "B", RawText: "1-01 (GBM 10100)"}
+	if len(sources) != 2 || sources["1-01"] == nil || *sources["1-01"] != *want {
+		t.Errorf("got sources %+v", sources)
+	}
+
+	path = filepath.Join(dir, "without.json")
+	if err := s.ExportToJSON(&service.ExportOptions{OutputPath: path, Format: "flat"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, present := exportedSources(t, path); present {
+		t.Error("source exported without -with-provenance")
+	}
+
+	// dry-run 直接导出解析结果
+	path = filepath.Join(dir, "nodes_with.json")
+	if err := service.ExportNodes(provenanceNodes(), &service.ExportOptions{OutputPath: path, Format: "flat", WithProvenance: true}); err != nil {
+		t.Fatal(err)
+	}
+	if sources, _ := exportedSources(t, path); len(sources) != 2 || sources["1"].RawText != "第一大类\n(GBM 10000)" || sources["1"].Cell() != "A3" {
+		t.Errorf("got dry-run sources %+v", sources)
+	}
+
+	path = filepath.Join(dir, "nodes_without.json")
+	nodes := provenanceNodes()
+	if err := service.ExportNodes(nodes, &service.ExportOptions{OutputPath: path, Format: "flat"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, present := exportedSources(t, path); present {
+		t.Error("dry-run source exported without -with-provenance")
+	}
+	if nodes[0].Source == nil {
+		t.Error("ExportNodes cleared the source of the parsed nodes")
+	}
+}
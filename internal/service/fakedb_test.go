@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/solisamicus/occstructor/pkg/database"
+)
+
+// fakeDB 内存中的 occupations 与 occupation_provenance 表，只支持仓库层用到的语句，
+// 用于在没有 MySQL 的环境中测试服务层
+type fakeDB struct {
+	mu         sync.Mutex
+	nodes      map[string][]driver.Value // seq → edition, seq, gbm, name, level, parent_seq, is_green, is_digital, definition
+	provenance map[string][]driver.Value // seq → seq, file_name, sheet_name, row_num, column_name, raw_text
+}
+
+var (
+	fakeDBsMu sync.Mutex
+	fakeDBs   = make(map[string]*fakeDB)
+)
+
+func init() {
+	sql.Register("occfake", fakeDriver{})
+}
+
+// openFakeDB 为每个测试打开一个独立的内存数据库
+func openFakeDB(t *testing.T) (*database.DB, *fakeDB) {
+	t.Helper()
+
+	store := &fakeDB{nodes: make(map[string][]driver.Value), provenance: make(map[string][]driver.Value)}
+	fakeDBsMu.Lock()
+	fakeDBs[t.Name()] = store
+	fakeDBsMu.Unlock()
+
+	db, err := sql.Open("occfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &database.DB{DB: db}, store
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeDBsMu.Lock()
+	defer fakeDBsMu.Unlock()
+	store, ok := fakeDBs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return &fakeConn{db: store}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(s.query, "INSERT INTO occupations "):
+		s.db.nodes[args[1].(string)] = args
+	case strings.HasPrefix(s.query, "INSERT INTO occupation_provenance "):
+		s.db.provenance[args[1].(string)] = args[1:]
+	case strings.HasPrefix(s.query, "DELETE FROM occupations "):
+		seq := args[1].(string)
+		if _, ok := s.db.nodes[seq]; !ok {
+			return driver.RowsAffected(0), nil
+		}
+		delete(s.db.nodes, seq)
+		delete(s.db.provenance, seq)
+		return driver.RowsAffected(1), nil
+	case strings.Contains(s.query, "occupation_tasks"):
+	default:
+		return nil, fmt.Errorf("unsupported statement: %s", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	rows := &fakeRows{}
+	switch {
+	case strings.Contains(s.query, "FROM occupations WHERE edition = ? ORDER BY level, seq"):
+		rows.columns = []string{"id", "edition", "seq", "gbm", "name", "level", "parent_seq", "is_green", "is_digital", "definition", "created_at", "updated_at"}
+		for _, n := range s.db.nodes {
+			now := time.Now()
+			rows.values = append(rows.values, []driver.Value{int64(len(rows.values) + 1), n[0], n[1], n[2], n[3], n[4], n[5], n[6], n[7], n[8], now, now})
+		}
+		sort.Slice(rows.values, func(i, j int) bool {
+			a, b := rows.values[i], rows.values[j]
+			if a[5] != b[5] {
+				return a[5].(int64) < b[5].(int64)
+			}
+			return a[2].(string) < b[2].(string)
+		})
+	case strings.Contains(s.query, "FROM occupation_provenance"):
+		rows.columns = []string{"seq", "file_name", "sheet_name", "row_num", "column_name", "raw_text"}
+		for _, p := range s.db.provenance {
+			rows.values = append(rows.values, p)
+		}
+	case strings.Contains(s.query, "FROM occupation_tasks"):
+		rows.columns = []string{"seq", "task"}
+	default:
+		return nil, fmt.Errorf("unsupported query: %s", s.query)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
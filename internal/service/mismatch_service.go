@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/parser"
+	"github.com/solisamicus/occstructor/internal/repository"
+)
+
+type MismatchService struct {
+	repo *repository.OccupationRepository
+}
+
+func NewMismatchService(repo *repository.OccupationRepository) *MismatchService {
+	return &MismatchService{repo: repo}
+}
+
+// BuildFixNodes 根据不匹配日志生成待入库的细类节点。
+// 优先使用人工填写的 fixed 配对；acceptSuggested 为 true 时，未修正且有建议配对的记录使用 suggested
+func (s *MismatchService) BuildFixNodes(mismatches []*parser.Mismatch, acceptSuggested bool) ([]*model.OccupationNode, error) {
+	var nodes []*model.OccupationNode
+
+	for _, m := range mismatches {
+		pairs := m.Fixed
+		origin := "fixed"
+		if len(pairs) == 0 {
+			if !acceptSuggested {
+				fmt.Printf("Line %-3d skipped: no fixed pairing\n", m.Line)
+				continue
+			}
+			if len(m.Suggested) == 0 {
+				fmt.Printf("Line %-3d skipped: no suggested pairing (%d codes, %d names)\n", m.Line, len(m.Codes), len(m.Names))
+				continue
+			}
+			pairs = m.Suggested
+			origin = "suggested"
+		}
+
+		if err := validatePairs(pairs); err != nil {
+			if origin == "fixed" {
+				return nil, fmt.Errorf("line %d: %w", m.Line, err)
+			}
+			fmt.Printf("Line %-3d skipped: suggested pairing is incomplete (%v)\n", m.Line, err)
+			continue
+		}
+
+		known := make(map[string]bool)
+		for _, code := range m.Codes {
+			known[code] = true
+		}
+		for _, pair := range pairs {
+			if !known[pair.Seq] {
+				fmt.Printf("Line %-3d warning: %s is not among the logged codes\n", m.Line, pair.Seq)
+			}
+		}
+
+		fmt.Printf("Line %-3d applying %d %s pairs\n", m.Line, len(pairs), origin)
+		nodes = append(nodes, m.Nodes(pairs)...)
+	}
+
+	return nodes, nil
+}
+
+func validatePairs(pairs []parser.Pairing) error {
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		if pair.Seq == "" {
+			return fmt.Errorf("missing code for %s", pair.Name)
+		}
+		if parser.DetailCodeRegex.FindString(pair.Seq) != pair.Seq {
+			return fmt.Errorf("invalid detail code %q", pair.Seq)
+		}
+		if pair.Name == "" {
+			return fmt.Errorf("missing name for %s", pair.Seq)
+		}
+		if seen[pair.Seq] {
+			return fmt.Errorf("duplicate code %s", pair.Seq)
+		}
+		seen[pair.Seq] = true
+	}
+	return nil
+}
+
+// ApplyFixes 将修正后的节点写入数据库
+func (s *MismatchService) ApplyFixes(nodes []*model.OccupationNode) error {
+	if err := s.repo.BatchInsert(nodes); err != nil {
+		return fmt.Errorf("failed to save fixes: %w", err)
+	}
+
+	fmt.Printf("Successfully saved %d corrected occupation records to database\n", len(nodes))
+	return nil
+}
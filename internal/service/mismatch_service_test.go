@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/parser"
+	"github.com/solisamicus/occstructor/internal/service"
+)
+
+func TestBuildFixNodes(t *testing.T) {
+	mismatches := func() []*parser.Mismatch {
+		return []*parser.Mismatch{
+			{
+				Line:  10,
+				Codes: []string{"2-03-01-00", "2-03-02-00"},
+				Fixed: []parser.Pairing{{Seq: "2-03-01-00", Name: "土壤肥料技术人员"}, {Seq: "2-03-02-00", Name: "农业技术指导人员"}},
+				// 有人工修正时忽略建议
+				Suggested: []parser.Pairing{{Seq: "2-03-01-00", Name: "错误"}, {Seq: "2-03-02-00", Name: "错误"}},
+			},
+			{
+				Line:      20,
+				Column:    "F",
+				Codes:     []string{"2-02-10-03"},
+				RawNames:  "计算机软件工程\n技术人员 S",
+				Suggested: []parser.Pairing{{Seq: "2-02-10-03", Name: "计算机软件工程技术人员", Digital: true}},
+			},
+			// 名称少于代码，没有建议
+			{Line: 30, Codes: []string{"2-02-10-05", "2-02-10-06"}, Names: []string{"信息系统分析工程技术人员"}},
+		}
+	}
+	s := service.NewMismatchService(nil)
+
+	nodes, err := s.BuildFixNodes(mismatches(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeSummary(nodes); got != "2-03-01-00 土壤肥料技术人员|2-03-02-00 农业技术指导人员" {
+		t.Errorf("got %s without -accept-suggested", got)
+	}
+
+	nodes, err = s.BuildFixNodes(mismatches(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeSummary(nodes); got != "2-03-01-00 土壤肥料技术人员|2-03-02-00 农业技术指导人员|2-02-10-03 计算机软件工程技术人员" {
+		t.Errorf("got %s with -accept-suggested", got)
+	}
+	detail := nodes[2]
+	if !detail.Digital || detail.Level != 4 || detail.ParentSeq == nil || *detail.ParentSeq != "2-02-10" ||
+		detail.Source.Row != 20 || detail.Source.Cell() != "F20" || detail.Source.RawText != "计算机软件工程\n技术人员 S" {
+		t.Errorf("got %+v", detail)
+	}
+
+	// 不完整的旧版建议被跳过，不完整的人工修正报错
+	incomplete := []*parser.Mismatch{{Line: 40, Suggested: []parser.Pairing{{Seq: "2-02-10-07", Name: "测试人员"}, {Seq: "2-02-10-08"}}}}
+	if nodes, err := s.BuildFixNodes(incomplete, true); err != nil || len(nodes) != 0 {
+		t.Errorf("got %d nodes and %v for an incomplete suggestion", len(nodes), err)
+	}
+
+	tests := []struct {
+		name  string
+		pairs []parser.Pairing
+		want  string
+	}{
+		{"missing code", []parser.Pairing{{Name: "测试人员"}}, "missing code"},
+		{"missing name", []parser.Pairing{{Seq: "2-02-10-07"}}, "missing name"},
+		{"invalid code", []parser.Pairing{{Seq: "2-02-10", Name: "测试人员"}}, "invalid detail code"},
+		{"duplicate code", []parser.Pairing{{Seq: "2-02-10-07", Name: "测试人员"}, {Seq: "2-02-10-07", Name: "测试员"}}, "duplicate code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.BuildFixNodes([]*parser.Mismatch{{Line: 50, Fixed: tt.pairs}}, false)
+			if err == nil || !strings.Contains(err.Error(), "line 50") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func nodeSummary(nodes []*model.OccupationNode) string {
+	var parts []string
+	for _, node := range nodes {
+		parts = append(parts, node.Seq+" "+node.Name)
+	}
+	return strings.Join(parts, "|")
+}
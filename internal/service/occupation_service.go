@@ -1,49 +1,91 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"github.com/solisamicus/occstructor/internal/corrections"
+	"github.com/solisamicus/occstructor/internal/model"
 	"github.com/solisamicus/occstructor/internal/parser"
 	"github.com/solisamicus/occstructor/internal/repository"
+	"github.com/solisamicus/occstructor/internal/validator"
 )
 
+// ErrValidation 解析结果存在 error 级别的校验问题
+var ErrValidation = errors.New("validation failed")
+
 type OccupationService struct {
-	repo   *repository.OccupationRepository
-	parser *parser.ExcelParser
+	repo    *repository.OccupationRepository
+	parser  *parser.ExcelParser
+	overlay *corrections.Overlay
 }
 
-func NewOccupationService(repo *repository.OccupationRepository, parser *parser.ExcelParser) *OccupationService {
+// NewOccupationService 创建导入服务，overlay 为 nil 时不应用人工修正
+func NewOccupationService(repo *repository.OccupationRepository, parser *parser.ExcelParser, overlay *corrections.Overlay) *OccupationService {
 	return &OccupationService{
-		repo:   repo,
-		parser: parser,
+		repo:    repo,
+		parser:  parser,
+		overlay: overlay,
 	}
 }
 
-func (s *OccupationService) ParseAndSave(filepath string) error {
-	result, err := s.parser.ParseFile(filepath)
+// Parse 解析文件、构建层级并应用人工修正，不访问数据库
+func (s *OccupationService) Parse(filepaths ...string) ([]*model.OccupationNode, *parser.ParseReport, error) {
+	result, report, err := s.parser.ParseFiles(filepaths...)
 	if err != nil {
-		return fmt.Errorf("failed to parse excel file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse excel file: %w", err)
 	}
 
 	allNodes := result.BuildHierarchy()
 
-	if err := s.repo.BatchInsert(allNodes); err != nil {
-		return fmt.Errorf("failed to save to database: %w", err)
+	if s.overlay != nil {
+		allNodes, report.Corrections = s.overlay.Apply(allNodes)
+		report.Corrections.Print()
+	}
+
+	report.Validation = validator.Validate(allNodes)
+	report.Validation.Print()
+
+	return allNodes, report, nil
+}
+
+// ParseAndSave 解析并入库，解析成功后即使入库失败也会返回解析报告
+func (s *OccupationService) ParseAndSave(filepaths ...string) (*parser.ParseReport, error) {
+	allNodes, report, err := s.Parse(filepaths...)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Validation.HasErrors() {
+		return report, fmt.Errorf("%w: %d errors, nothing saved", ErrValidation, report.Validation.Errors)
+	}
+
+	// 修正中的删除与写入在同一事务中，写入失败时不会留下已删除的记录
+	var deletedSeqs []string
+	if s.overlay != nil {
+		deletedSeqs = s.overlay.DeletedSeqs()
+	}
+	deleted, err := s.repo.ReplaceNodes(deletedSeqs, allNodes)
+	if err != nil {
+		return report, fmt.Errorf("failed to save to database: %w", err)
+	}
+	if deleted > 0 {
+		fmt.Printf("Deleted %d occupation records by corrections\n", deleted)
 	}
 
 	fmt.Printf("Successfully saved %d occupation records to database\n", len(allNodes))
 
 	stats, err := s.repo.GetStats()
 	if err != nil {
-		return fmt.Errorf("failed to get stats: %w", err)
+		return report, fmt.Errorf("failed to get stats: %w", err)
 	}
 
-	fmt.Println("Database statistics:")
-	levelNames := map[int]string{1: "Major", 2: "Middle", 3: "Minor", 4: "Detail"}
-	for level := 1; level <= 4; level++ {
+	fmt.Printf("Database statistics (edition %s):\n", s.repo.Edition())
+	levelNames := map[int]string{1: "Major", 2: "Middle", 3: "Minor", 4: "Detail", 5: "Sub-type"}
+	for level := 1; level <= 5; level++ {
 		if count, exists := stats[level]; exists {
 			fmt.Printf("  %s categories: %d\n", levelNames[level], count)
 		}
 	}
 
-	return nil
+	return report, nil
 }
@@ -0,0 +1,25 @@
+package validator
+
+import "fmt"
+
+// Report 校验结果，按严重程度和编号排序
+type Report struct {
+	Errors   int     `json:"errors"`
+	Warnings int     `json:"warnings"`
+	Issues   []Issue `json:"issues"`
+}
+
+func (r *Report) HasErrors() bool {
+	return r.Errors > 0
+}
+
+func (r *Report) Print() {
+	fmt.Printf("Validation: %d errors, %d warnings\n", r.Errors, r.Warnings)
+	for _, issue := range r.Issues {
+		location := ""
+		if issue.Source != nil {
+			location = " (" + issue.Source.String() + ")"
+		}
+		fmt.Printf("  %-7s %-10s %-12s %s%s\n", issue.Severity, issue.Rule, issue.Seq, issue.Message, location)
+	}
+}
@@ -0,0 +1,248 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/solisamicus/occstructor/internal/model"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// 规则名称，写入报告便于按规则筛选
+const (
+	RuleOrphan    = "orphan"
+	RuleDuplicate = "duplicate"
+	RuleLevel     = "level"
+	RuleParent    = "parent"
+	RuleGap       = "gap"
+	RuleGBMFormat = "gbm_format"
+	RuleGBMSeq    = "gbm_seq"
+	RuleGBMParent = "gbm_parent"
+)
+
+const (
+	unassignedSegment = 0  // 无下级细分时编为 00，如 1-01-00
+	otherCategorySeg  = 99 // "其他"类固定编为 99，不参与连续性检查
+)
+
+var gbmRegex = regexp.MustCompile(`^\d+$`)
+
+// Issue 一条校验问题
+type Issue struct {
+	Severity Severity          `json:"severity"`
+	Rule     string            `json:"rule"`
+	Seq      string            `json:"seq"`
+	Message  string            `json:"message"`
+	Source   *model.Provenance `json:"source,omitempty"`
+}
+
+// Validate 校验 BuildHierarchy 之后的节点列表
+func Validate(nodes []*model.OccupationNode) *Report {
+	report := &Report{Issues: []Issue{}}
+
+	add := func(severity Severity, rule string, node *model.OccupationNode, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, Issue{
+			Severity: severity,
+			Rule:     rule,
+			Seq:      node.Seq,
+			Message:  fmt.Sprintf(format, args...),
+			Source:   node.Source,
+		})
+	}
+
+	bySeq := make(map[string]*model.OccupationNode, len(nodes))
+	children := make(map[string][]*model.OccupationNode)
+	for _, node := range nodes {
+		if first, ok := bySeq[node.Seq]; ok {
+			add(SeverityError, RuleDuplicate, node, "duplicate of %q", first.Name)
+			continue
+		}
+		bySeq[node.Seq] = node
+
+		parentSeq := ""
+		if node.ParentSeq != nil {
+			parentSeq = *node.ParentSeq
+		}
+		children[parentSeq] = append(children[parentSeq], node)
+	}
+
+	for _, node := range nodes {
+		if bySeq[node.Seq] != node {
+			continue
+		}
+
+		if depth := model.LevelOf(node.Seq); node.Level != depth {
+			add(SeverityError, RuleLevel, node, "level %d does not match seq depth %d", node.Level, depth)
+		}
+
+		parentSeq := ""
+		if node.ParentSeq != nil {
+			parentSeq = *node.ParentSeq
+		}
+		if want := model.GetParentSeq(node.Seq); parentSeq != want {
+			add(SeverityWarning, RuleParent, node, "parent %q differs from %q implied by seq", parentSeq, want)
+		}
+
+		var parent *model.OccupationNode
+		if parentSeq != "" {
+			var ok bool
+			if parent, ok = bySeq[parentSeq]; !ok {
+				add(SeverityError, RuleOrphan, node, "parent %s not found", parentSeq)
+			}
+		}
+
+		checkGBM(node, parent, add)
+	}
+
+	for parentSeq, siblings := range children {
+		if missing := missingSiblings(parentSeq, siblings); len(missing) > 0 {
+			first := siblings[0]
+			for _, s := range siblings[1:] {
+				if s.Seq < first.Seq {
+					first = s
+				}
+			}
+			add(SeverityWarning, RuleGap, first, "siblings under %q are not contiguous, missing %s",
+				parentSeq, strings.Join(missing, ", "))
+		}
+	}
+
+	report.finish()
+	return report
+}
+
+func checkGBM(node, parent *model.OccupationNode, add func(Severity, string, *model.OccupationNode, string, ...interface{})) {
+	if node.GBM == "" {
+		return
+	}
+
+	// 大典原文中大类 GBM 写作两位(如 10)，与补足5位的 10000 等价
+	if node.Level == 1 && node.GBM == node.Seq+"0" {
+		return
+	}
+
+	want := model.DeriveGBM(node.Seq)
+	if !gbmRegex.MatchString(node.GBM) {
+		add(SeverityWarning, RuleGBMFormat, node, "gbm %q is not numeric%s", node.GBM, describeCorruption(node.GBM, want))
+		return
+	}
+	if want != "" && len(node.GBM) != len(want) {
+		add(SeverityWarning, RuleGBMFormat, node, "gbm %q is not %d digits%s", node.GBM, len(want), describeCorruption(node.GBM, want))
+		return
+	}
+
+	if want != "" && node.GBM != want {
+		add(SeverityWarning, RuleGBMSeq, node, "gbm %s does not match %s derived from seq%s", node.GBM, want, describeCorruption(node.GBM, want))
+		return
+	}
+
+	if parent == nil || parent.GBM == "" || !gbmRegex.MatchString(parent.GBM) {
+		return
+	}
+
+	// 子类 GBM 以上级 GBM 去掉末尾 0 后的部分开头，如 20200 → 20210
+	prefix := strings.TrimRight(parent.GBM, "0")
+	if !strings.HasPrefix(node.GBM, prefix) {
+		add(SeverityWarning, RuleGBMParent, node, "gbm %s does not extend parent gbm %s", node.GBM, parent.GBM)
+	}
+}
+
+// describeCorruption 判断 GBM 与推导值的差异是否像 OCR 错误：丢失位数、单个数字识别错误或混入易混字母
+func describeCorruption(got, want string) string {
+	if want == "" {
+		return ""
+	}
+
+	switch {
+	case strings.ContainsAny(got, "OoIl|"):
+		return ", likely OCR letter/digit confusion"
+	case len(got) < len(want) && isSubsequence(got, want):
+		return ", likely OCR dropped digits"
+	case len(got) == len(want):
+		diff := 0
+		for i := range got {
+			if got[i] != want[i] {
+				diff++
+			}
+		}
+		if diff == 1 {
+			return ", likely OCR misread one digit"
+		}
+	}
+	return ""
+}
+
+func isSubsequence(short, long string) bool {
+	i := 0
+	for j := 0; j < len(long) && i < len(short); j++ {
+		if short[i] == long[j] {
+			i++
+		}
+	}
+	return i == len(short)
+}
+
+// missingSiblings 返回同级编号末段中缺失的编号，00 与 99 不要求连续
+func missingSiblings(parentSeq string, siblings []*model.OccupationNode) []string {
+	present := make(map[int]bool)
+	maxSeg, width := 0, 0
+	for _, node := range siblings {
+		parts := strings.Split(node.Seq, "-")
+		last := parts[len(parts)-1]
+		seg, err := strconv.Atoi(last)
+		if err != nil || seg == unassignedSegment || seg == otherCategorySeg {
+			continue
+		}
+		present[seg] = true
+		if seg > maxSeg {
+			maxSeg = seg
+		}
+		width = len(last)
+	}
+
+	var missing []string
+	for seg := 1; seg < maxSeg; seg++ {
+		if present[seg] {
+			continue
+		}
+		last := fmt.Sprintf("%0*d", width, seg)
+		if parentSeq == "" {
+			missing = append(missing, last)
+		} else {
+			missing = append(missing, parentSeq+"-"+last)
+		}
+	}
+	return missing
+}
+
+func (r *Report) finish() {
+	for _, issue := range r.Issues {
+		switch issue.Severity {
+		case SeverityError:
+			r.Errors++
+		case SeverityWarning:
+			r.Warnings++
+		}
+	}
+
+	rank := map[Severity]int{SeverityError: 0, SeverityWarning: 1}
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		a, b := r.Issues[i], r.Issues[j]
+		if a.Severity != b.Severity {
+			return rank[a.Severity] < rank[b.Severity]
+		}
+		if a.Seq != b.Seq {
+			return a.Seq < b.Seq
+		}
+		return a.Rule < b.Rule
+	})
+}
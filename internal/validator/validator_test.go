@@ -0,0 +1,98 @@
+package validator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/solisamicus/occstructor/internal/model"
+	"github.com/solisamicus/occstructor/internal/validator"
+)
+
+func TestValidate(t *testing.T) {
+	result := &model.ParseResult{
+		Majors:  []*model.OccupationNode{{Seq: "2", GBM: "20000", Name: "专业技术人员", Level: 1}},
+		Middles: []*model.OccupationNode{{Seq: "2-02", GBM: "20200", Name: "工程技术人员", Level: 2}},
+		Minors: []*model.OccupationNode{
+			{Seq: "2-02-10", GBM: "20210", Name: "信息和通信工程技术人员", Level: 3},
+			{Seq: "2-03-01", GBM: "20301", Name: "农业技术人员", Level: 3},
+		},
+		SubMinors: []*model.OccupationNode{
+			{Seq: "2-02-10-01", Name: "通信工程技术人员", Level: 4},
+			{Seq: "2-02-10-03", Name: "计算机软件工程技术人员", Level: 4},
+			{Seq: "2-02-10-99", Name: "其他信息和通信工程技术人员", Level: 4},
+			{Seq: "2-02-10-04", Name: "计算机网络工程技术人员", Level: 3},
+		},
+	}
+	nodes := result.BuildHierarchy()
+	nodes = append(nodes, &model.OccupationNode{Seq: "2-02", GBM: "3O200", Name: "重复", Level: 2, ParentSeq: nodes[0].ParentSeq})
+
+	report := validator.Validate(nodes)
+
+	type key struct{ rule, seq string }
+	got := make(map[key]validator.Severity)
+	for _, issue := range report.Issues {
+		got[key{issue.Rule, issue.Seq}] = issue.Severity
+	}
+
+	want := map[key]validator.Severity{
+		{validator.RuleOrphan, "2-03-01"}:    validator.SeverityError,
+		{validator.RuleDuplicate, "2-02"}:    validator.SeverityError,
+		{validator.RuleLevel, "2-02-10-04"}:  validator.SeverityError,
+		{validator.RuleGap, "2-02-10-01"}:    validator.SeverityWarning,
+		{validator.RuleGap, "2-02"}:          validator.SeverityWarning,
+		{validator.RuleGBMParent, "2-03-01"}: "",
+	}
+	for k, severity := range want {
+		if severity == "" {
+			if _, ok := got[k]; ok {
+				t.Errorf("unexpected %s issue for %s", k.rule, k.seq)
+			}
+			continue
+		}
+		if got[k] != severity {
+			t.Errorf("%s %s: got %q, want %q", k.rule, k.seq, got[k], severity)
+		}
+	}
+
+	if report.Errors != 3 || !report.HasErrors() {
+		t.Errorf("got %d errors, want 3: %+v", report.Errors, report.Issues)
+	}
+	if first := report.Issues[0]; first.Severity != validator.SeverityError {
+		t.Errorf("errors should sort first, got %+v", first)
+	}
+}
+
+func TestValidateGBM(t *testing.T) {
+	result := &model.ParseResult{
+		// 两位的大类 GBM 是原文写法，不是 OCR 错误
+		Majors: []*model.OccupationNode{{Seq: "1", GBM: "10", Level: 1}, {Seq: "2", GBM: "200", Level: 1}},
+		Middles: []*model.OccupationNode{
+			{Seq: "1-01", GBM: "10100", Level: 2},
+			{Seq: "1-02", GBM: "1O200", Level: 2},
+			{Seq: "1-03", GBM: "10800", Level: 2},
+		},
+		SubMinors: []*model.OccupationNode{{Seq: "1-01-00-01", GBM: "1010001", Level: 4}},
+	}
+
+	report := validator.Validate(result.BuildHierarchy())
+
+	got := make(map[string]string)
+	for _, issue := range report.Issues {
+		if strings.HasPrefix(issue.Rule, "gbm") {
+			got[issue.Seq] = issue.Rule + ": " + issue.Message
+		}
+	}
+	want := map[string]string{
+		"2":    "gbm_format: gbm \"200\" is not 5 digits, likely OCR dropped digits",
+		"1-02": "gbm_format: gbm \"1O200\" is not numeric, likely OCR letter/digit confusion",
+		"1-03": "gbm_seq: gbm 10800 does not match 10300 derived from seq, likely OCR misread one digit",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %v", got)
+	}
+	for seq, message := range want {
+		if got[seq] != message {
+			t.Errorf("%s: got %q, want %q", seq, got[seq], message)
+		}
+	}
+}
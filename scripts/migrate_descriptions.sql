@@ -0,0 +1,15 @@
+-- 为已有数据库增加职业定义、主要工作任务及工种(层级 5)
+USE occupation_db;
+
+ALTER TABLE occupations
+    MODIFY COLUMN level TINYINT NOT NULL COMMENT '层级: 1-大类, 2-中类, 3-小类, 4-细类, 5-工种',
+    ADD COLUMN definition TEXT COMMENT '职业定义' AFTER is_digital;
+
+CREATE TABLE IF NOT EXISTS occupation_tasks (
+    edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本',
+    seq VARCHAR(20) NOT NULL COMMENT '职业编号',
+    position SMALLINT NOT NULL COMMENT '任务序号，从1开始',
+    task TEXT NOT NULL COMMENT '主要工作任务',
+    PRIMARY KEY (edition, seq, position),
+    CONSTRAINT fk_tasks_occupation FOREIGN KEY (edition, seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
@@ -0,0 +1,37 @@
+-- 为已有数据库增加大典版本维度，原有数据归入 2022 版
+-- 旧版 setup.sql 中的外键没有命名，先从 information_schema 查出实际名称再删除
+USE occupation_db;
+
+SET @fk = (SELECT CONSTRAINT_NAME FROM information_schema.KEY_COLUMN_USAGE
+           WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'occupation_provenance'
+             AND COLUMN_NAME = 'seq' AND REFERENCED_TABLE_NAME = 'occupations' LIMIT 1);
+SET @stmt = IF(@fk IS NULL, 'DO 0', CONCAT('ALTER TABLE occupation_provenance DROP FOREIGN KEY `', @fk, '`'));
+PREPARE drop_fk FROM @stmt;
+EXECUTE drop_fk;
+DEALLOCATE PREPARE drop_fk;
+
+SET @fk = (SELECT CONSTRAINT_NAME FROM information_schema.KEY_COLUMN_USAGE
+           WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'occupations'
+             AND COLUMN_NAME = 'parent_seq' AND REFERENCED_TABLE_NAME = 'occupations' LIMIT 1);
+SET @stmt = IF(@fk IS NULL, 'DO 0', CONCAT('ALTER TABLE occupations DROP FOREIGN KEY `', @fk, '`'));
+PREPARE drop_fk FROM @stmt;
+EXECUTE drop_fk;
+DEALLOCATE PREPARE drop_fk;
+
+ALTER TABLE occupations
+    ADD COLUMN edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本: 1999, 2015, 2022' AFTER id,
+    DROP INDEX seq,
+    DROP INDEX idx_parent_seq,
+    DROP INDEX idx_level,
+    ADD UNIQUE KEY uk_edition_seq (edition, seq),
+    ADD INDEX idx_parent_seq (edition, parent_seq),
+    ADD INDEX idx_level (edition, level);
+
+ALTER TABLE occupations
+    ADD CONSTRAINT fk_occupations_parent FOREIGN KEY (edition, parent_seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE;
+
+ALTER TABLE occupation_provenance
+    ADD COLUMN edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本' FIRST,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (edition, seq),
+    ADD CONSTRAINT fk_provenance_occupation FOREIGN KEY (edition, seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE;
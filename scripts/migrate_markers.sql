@@ -0,0 +1,6 @@
+-- 为已有数据库增加绿色职业(L)、数字职业(S)标识，需在其他迁移脚本之前执行
+USE occupation_db;
+
+ALTER TABLE occupations
+    ADD COLUMN is_green BOOLEAN NOT NULL DEFAULT FALSE COMMENT '绿色职业标识(L)' AFTER parent_seq,
+    ADD COLUMN is_digital BOOLEAN NOT NULL DEFAULT FALSE COMMENT '数字职业标识(S)' AFTER is_green;
@@ -5,14 +5,57 @@ USE occupation_db;
 
 CREATE TABLE IF NOT EXISTS occupations (
     id BIGINT AUTO_INCREMENT PRIMARY KEY,
-    seq VARCHAR(20) NOT NULL UNIQUE COMMENT '职业编号',
+    edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本: 1999, 2015, 2022',
+    seq VARCHAR(20) NOT NULL COMMENT '职业编号',
     gbm VARCHAR(20) COMMENT 'GBM编码',
     name VARCHAR(200) NOT NULL COMMENT '职业名称',
-    level TINYINT NOT NULL COMMENT '层级: 1-大类, 2-中类, 3-小类, 4-细类',
+    level TINYINT NOT NULL COMMENT '层级: 1-大类, 2-中类, 3-小类, 4-细类, 5-工种',
     parent_seq VARCHAR(20) COMMENT '父级编号',
+    is_green BOOLEAN NOT NULL DEFAULT FALSE COMMENT '绿色职业标识(L)',
+    is_digital BOOLEAN NOT NULL DEFAULT FALSE COMMENT '数字职业标识(S)',
+    definition TEXT COMMENT '职业定义',
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-    INDEX idx_parent_seq (parent_seq),
-    INDEX idx_level (level),
-    FOREIGN KEY (parent_seq) REFERENCES occupations(seq) ON DELETE CASCADE
-    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
\ No newline at end of file
+    UNIQUE KEY uk_edition_seq (edition, seq),
+    INDEX idx_parent_seq (edition, parent_seq),
+    INDEX idx_level (edition, level),
+    CONSTRAINT fk_occupations_parent FOREIGN KEY (edition, parent_seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS occupation_tasks (
+    edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本',
+    seq VARCHAR(20) NOT NULL COMMENT '职业编号',
+    position SMALLINT NOT NULL COMMENT '任务序号，从1开始',
+    task TEXT NOT NULL COMMENT '主要工作任务',
+    PRIMARY KEY (edition, seq, position),
+    CONSTRAINT fk_tasks_occupation FOREIGN KEY (edition, seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS occupation_provenance (
+    edition VARCHAR(10) NOT NULL DEFAULT '2022' COMMENT '大典版本',
+    seq VARCHAR(20) NOT NULL COMMENT '职业编号',
+    file_name VARCHAR(255) COMMENT '源文件路径',
+    sheet_name VARCHAR(100) NOT NULL COMMENT '工作表名称',
+    row_num INT NOT NULL COMMENT 'Excel行号',
+    column_name VARCHAR(10) NOT NULL COMMENT 'Excel列号',
+    raw_text TEXT COMMENT '单元格原文',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (edition, seq),
+    CONSTRAINT fk_provenance_occupation FOREIGN KEY (edition, seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS occupation_crosswalk (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    source_edition VARCHAR(10) NOT NULL COMMENT '源大典版本',
+    source_seq VARCHAR(20) NOT NULL COMMENT '源职业编号',
+    target_system VARCHAR(10) NOT NULL COMMENT '目标体系: isco08 或大典版本号',
+    target_code VARCHAR(20) NOT NULL COMMENT '目标编码',
+    relation VARCHAR(10) NOT NULL DEFAULT 'exact' COMMENT '关系: exact, broader, narrower, partial',
+    note VARCHAR(255) COMMENT '备注',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_crosswalk (source_edition, source_seq, target_system, target_code),
+    INDEX idx_target (target_system, target_code),
+    CONSTRAINT fk_crosswalk_source FOREIGN KEY (source_edition, source_seq) REFERENCES occupations(edition, seq) ON DELETE CASCADE
+    ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;